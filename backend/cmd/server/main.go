@@ -34,6 +34,8 @@ func main() {
 	mux.HandleFunc("/api/maze/delete", middleware.RequireAuth(handlers.HandleDeleteMaze))
 	mux.HandleFunc("/api/maze/solve", handlers.HandleSolveMaze)
 	mux.HandleFunc("/api/maze/render", handlers.HandleRenderMaze)
+	mux.HandleFunc("/api/maze/stream", handlers.HandleStreamMaze)
+	mux.HandleFunc("/api/maze/algorithms", handlers.HandleListAlgorithms)
 	mux.HandleFunc("/api/maze/thumbnail", handlers.HandleUpdateThumbnail)
 
     // User & Profile Endpoints
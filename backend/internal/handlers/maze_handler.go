@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"math/rand"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/IZO-Ong/gridgo/internal/db"
 	"github.com/IZO-Ong/gridgo/internal/maze"
@@ -13,6 +17,135 @@ import (
 	"github.com/IZO-Ong/gridgo/internal/models"
 )
 
+// formParams collects the submitted form values as generator parameters.
+func formParams(r *http.Request) maze.Params {
+	params := maze.Params{}
+	for key, values := range r.Form {
+		if len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+	return params
+}
+
+// acceptsImage reports whether a generator takes an uploaded image.
+func acceptsImage(gen maze.Generator) bool {
+	for _, p := range gen.Params() {
+		if p.Type == "file" && p.Name == "image" {
+			return true
+		}
+	}
+	return false
+}
+
+// formFloat reads an optional float form value, falling back to def.
+func formFloat(r *http.Request, key string, def float64) (float64, error) {
+	v := r.FormValue(key)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// formInt reads an optional integer form value, falling back to def.
+func formInt(r *http.Request, key string, def int) (int, error) {
+	v := r.FormValue(key)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// formDifficulty reads the optional difficulty band a generated maze must
+// land in, and how long the server may search for one.
+func formDifficulty(r *http.Request) (maze.Difficulty, time.Duration, error) {
+	var d maze.Difficulty
+	var err error
+	floats := map[string]*float64{
+		"min_complexity": &d.MinComplexity,
+		"max_complexity": &d.MaxComplexity,
+	}
+	for key, dst := range floats {
+		if *dst, err = formFloat(r, key, 0); err != nil {
+			return d, 0, err
+		}
+	}
+	ints := map[string]*int{
+		"min_solution":  &d.MinSolution,
+		"max_solution":  &d.MaxSolution,
+		"min_dead_ends": &d.MinDeadEnds,
+		"max_dead_ends": &d.MaxDeadEnds,
+	}
+	for key, dst := range ints {
+		if *dst, err = formInt(r, key, 0); err != nil {
+			return d, 0, err
+		}
+	}
+	if err := d.Validate(); err != nil {
+		return d, 0, err
+	}
+
+	budgetMS, err := formInt(r, "budget_ms", int(maze.DefaultSearchBudget/time.Millisecond))
+	if err != nil {
+		return d, 0, err
+	}
+	budget := time.Duration(budgetMS) * time.Millisecond
+	if budget <= 0 || budget > maze.MaxSearchBudget {
+		return d, 0, fmt.Errorf("budget_ms must be between 1 and %d", maze.MaxSearchBudget/time.Millisecond)
+	}
+	return d, budget, nil
+}
+
+// formTerrain reads the "terrain" source and, for image terrain, derives
+// cell costs from an uploaded "terrain_image". The costs are nil for every
+// other source.
+func formTerrain(r *http.Request, m *maze.Maze) (string, [][]int, error) {
+	terrain := r.FormValue("terrain")
+	if err := maze.ValidateTerrain(terrain); err != nil || terrain != maze.TerrainImage {
+		return terrain, nil, err
+	}
+	file, _, err := r.FormFile("terrain_image")
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+	costs, err := m.TerrainFromImage(file)
+	return terrain, costs, err
+}
+
+// formMask builds a shape mask from an uploaded "mask" silhouette and/or a
+// "mask_cells" JSON list of [row, col] cells to disable. It returns nil
+// when neither is supplied.
+func formMask(r *http.Request, m *maze.Maze) ([][]bool, error) {
+	var mask [][]bool
+	if file, _, err := r.FormFile("mask"); err == nil {
+		defer file.Close()
+		if mask, err = m.MaskFromImage(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if v := r.FormValue("mask_cells"); v != "" {
+		var cells [][2]int
+		if err := json.Unmarshal([]byte(v), &cells); err != nil {
+			return nil, err
+		}
+		listed, err := m.MaskFromCells(cells)
+		if err != nil {
+			return nil, err
+		}
+		if mask == nil {
+			return listed, nil
+		}
+		for r := range mask {
+			for c := range mask[r] {
+				mask[r][c] = mask[r][c] || listed[r][c]
+			}
+		}
+	}
+	return mask, nil
+}
+
 func HandleGenerateMaze(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -23,26 +156,194 @@ func HandleGenerateMaze(w http.ResponseWriter, r *http.Request) {
 	rows, _ := strconv.Atoi(r.FormValue("rows"))
 	cols, _ := strconv.Atoi(r.FormValue("cols"))
 	genType := r.FormValue("type")
+	if err := maze.ValidateDimensions(rows, cols); err != nil {
+		http.Error(w, "INVALID_DIMENSIONS", http.StatusBadRequest)
+		return
+	}
+
+	seed := maze.NewSeed()
+	if v := r.FormValue("seed"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "INVALID_SEED", http.StatusBadRequest)
+			return
+		}
+		seed = parsed
+	}
+
+	braid, err := formFloat(r, "braid", 0)
+	if err != nil || braid < 0 || braid > 1 {
+		http.Error(w, "INVALID_BRAID", http.StatusBadRequest)
+		return
+	}
+
+	oneWay, err := formFloat(r, "one_way", 0)
+	if err != nil || oneWay < 0 || oneWay > 1 {
+		http.Error(w, "INVALID_ONE_WAY", http.StatusBadRequest)
+		return
+	}
+
+	portals, err := formInt(r, "portals", 0)
+	if err != nil || portals < 0 || portals > maze.MaxPortals {
+		http.Error(w, "INVALID_PORTALS", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := formInt(r, "keys", 0)
+	if err != nil || keys < 0 || keys > maze.MaxKeys {
+		http.Error(w, "INVALID_KEYS", http.StatusBadRequest)
+		return
+	}
+
+	levels := 1
+	if v := r.FormValue("levels"); v != "" {
+		if levels, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "INVALID_DIMENSIONS", http.StatusBadRequest)
+			return
+		}
+	}
+
+	difficulty, budget, err := formDifficulty(r)
+	if err != nil {
+		http.Error(w, "INVALID_DIFFICULTY", http.StatusBadRequest)
+		return
+	}
+
+	placement := maze.Placement{Strategy: r.FormValue("placement")}
+	placement.MinPath, err = formInt(r, "min_path", 0)
+	if err == nil {
+		err = placement.Validate()
+	}
+	if err != nil {
+		http.Error(w, "INVALID_PLACEMENT", http.StatusBadRequest)
+		return
+	}
 
-	myMaze := maze.NewMaze(rows, cols)
+	// this maze validates the grid settings and becomes the first attempt
+	// at the difficulty; any later attempts are built afresh below
+	topology, wrap := r.FormValue("topology"), r.FormValue("wrap")
+	myMaze, err := maze.NewMazeWithLevels(rows, cols, levels, topology)
+	if errors.Is(err, maze.ErrUnknownTopology) {
+		http.Error(w, "UNKNOWN_TOPOLOGY", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "INVALID_DIMENSIONS", http.StatusBadRequest)
+		return
+	}
+	if err := myMaze.SetWrap(wrap); err != nil {
+		http.Error(w, "INVALID_WRAP", http.StatusBadRequest)
+		return
+	}
+	mask, err := formMask(r, myMaze)
+	if err == nil && mask != nil {
+		err = myMaze.SetMask(mask)
+	}
+	if err != nil {
+		http.Error(w, "INVALID_MASK", http.StatusBadRequest)
+		return
+	}
+	terrain, terrainCosts, err := formTerrain(r, myMaze)
+	if err != nil {
+		http.Error(w, "INVALID_TERRAIN", http.StatusBadRequest)
+		return
+	}
+	trace := r.FormValue("trace")
 	var originalWeights map[string]int
 
-	switch genType {
-	case "image":
-		file, _, _ := r.FormFile("image")
-		defer file.Close()
-		weights, _ := maze.GetEdgeWeights(file, rows, cols)
-		originalWeights = weights
-		myMaze.GenerateImageMaze(weights)
-	case "kruskal":
-		myMaze.GenerateKruskal()
-	case "recursive":
-		myMaze.GenerateRecursive(0, 0)
+	gen, err := maze.NewGenerator(genType, formParams(r))
+	if errors.Is(err, maze.ErrUnknownGenerator) {
+		http.Error(w, "UNKNOWN_ALGORITHM", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if acceptsImage(gen) {
+		if file, _, err := r.FormFile("image"); err == nil {
+			defer file.Close()
+			weights, err := maze.GetEdgeWeights(file, rows, cols)
+			if err != nil {
+				http.Error(w, "INVALID_IMAGE", http.StatusBadRequest)
+				return
+			}
+			originalWeights = weights
+		}
+	}
+
+	first := myMaze
+	build := func(ctx context.Context, seed int64) (*maze.Maze, error) {
+		m := first
+		first = nil
+		if m == nil {
+			var err error
+			if m, err = maze.NewMazeWithLevels(rows, cols, levels, topology); err != nil {
+				return nil, err
+			}
+			if err := m.SetWrap(wrap); err != nil {
+				return nil, err
+			}
+			if mask != nil {
+				if err := m.SetMask(mask); err != nil {
+					return nil, err
+				}
+			}
+		}
+		m.SetSeed(seed)
+		if trace == "1" || trace == "true" {
+			m.EnableTrace()
+		}
+		m.Weights = originalWeights
+
+		if err := gen.Generate(ctx, m, m.Rand()); err != nil {
+			return nil, err
+		}
+		if braid > 0 {
+			m.Braid(braid)
+		}
+		m.SyncGridToWeights(originalWeights)
+		if terrainCosts != nil {
+			if err := m.SetTerrain(terrainCosts); err != nil {
+				return nil, err
+			}
+		} else if terrain == maze.TerrainNoise {
+			m.AddNoiseTerrain()
+		}
+		if err := m.SetStartEnd(placement); err != nil {
+			return nil, err
+		}
+		if portals > 0 {
+			m.PlacePortals(portals)
+		}
+		if oneWay > 0 {
+			m.AddOneWays(oneWay)
+		}
+		if keys > 0 {
+			m.PlaceKeys(keys)
+		}
+		return m, nil
+	}
+
+	// the budget only bounds a search; without a band the first maze is
+	// kept however long it takes to build
+	ctx := r.Context()
+	if !difficulty.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+	myMaze, err = maze.SearchDifficulty(ctx, difficulty, seed, build)
+	if r.Context().Err() != nil {
+		// the client has gone, so there is no one to answer
+		return
+	} else if errors.Is(err, maze.ErrDifficultyNotMet) {
+		http.Error(w, "DIFFICULTY_NOT_MET", http.StatusUnprocessableEntity)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	myMaze.SyncGridToWeights(originalWeights)
-	myMaze.SetRandomStartEnd()
-	
 	weightsBytes, _ := json.Marshal(myMaze.Weights)
 	stats := myMaze.CalculateStats()
 	mazeID := fmt.Sprintf("M-%d-X", rand.Intn(9000)+1000)
@@ -51,10 +352,14 @@ func HandleGenerateMaze(w http.ResponseWriter, r *http.Request) {
 	
 	dbMaze := models.Maze{
 		ID: mazeID, WeightsJSON: string(weightsBytes),
-		Rows: rows, Cols: cols,
+		WallsData: myMaze.EncodeWalls(), MaskData: myMaze.EncodeMask(), Algorithm: genType,
+		FeaturesData: myMaze.EncodeFeatures(), PortalsData: myMaze.EncodePortals(),
+		Topology: myMaze.Topology, Levels: myMaze.Levels, Wrap: myMaze.Wrap,
+		Placement: myMaze.Placement,
+		Rows: rows, Cols: cols, Seed: myMaze.Seed,
 		StartRow: myMaze.Start[0], StartCol: myMaze.Start[1],
 		EndRow: myMaze.End[0], EndCol: myMaze.End[1],
-		Complexity: stats.Complexity, DeadEnds: stats.DeadEnds,
+		Complexity: stats.Complexity, DeadEnds: stats.DeadEnds, Loops: stats.Loops,
 	}
 
 	if userID != "" { dbMaze.CreatorID = &userID }
@@ -64,6 +369,76 @@ func HandleGenerateMaze(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(myMaze)
 }
 
+// Streamed mazes only hold one row in memory, so rows are limited by
+// response size rather than by server memory. Text spends about eight bytes
+// a cell and a PNG one byte a pixel before compression, so each format gets
+// its own cap, the PNG one matching the text cap at the default cell size.
+const (
+	maxStreamRows      = 5_000_000
+	maxStreamCols      = 2000
+	maxStreamTextCells = 16_000_000
+	maxStreamPNGPixels = 1_600_000_000
+)
+
+// HandleStreamMaze generates an Eller's maze row by row and streams it
+// straight to the response as a PNG or plain text, without persisting it.
+func HandleStreamMaze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	rows, _ := strconv.Atoi(q.Get("rows"))
+	cols, _ := strconv.Atoi(q.Get("cols"))
+	if rows < 1 || cols < 1 || rows > maxStreamRows || cols > maxStreamCols {
+		http.Error(w, "INVALID_DIMENSIONS", http.StatusBadRequest)
+		return
+	}
+
+	seed := maze.NewSeed()
+	if v := q.Get("seed"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "INVALID_SEED", http.StatusBadRequest)
+			return
+		}
+		seed = parsed
+	}
+
+	stream := maze.NewEllerStream(rows, cols, seed)
+	w.Header().Set("X-Maze-Seed", strconv.FormatInt(seed, 10))
+
+	// headers are gone by the time a write fails, so failures can only be
+	// logged
+	switch q.Get("format") {
+	case "text":
+		if rows > maxStreamTextCells/cols {
+			http.Error(w, "INVALID_DIMENSIONS", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := stream.WriteText(w); err != nil {
+			log.Printf("Stream Failure: %v", err)
+		}
+	case "", "png":
+		cellSize, err := strconv.Atoi(q.Get("cell"))
+		if err != nil || cellSize < 2 || cellSize > 50 {
+			cellSize = 10
+		}
+		if rows > maxStreamPNGPixels/(cols*cellSize*cellSize) {
+			http.Error(w, "INVALID_DIMENSIONS", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		if err := stream.WritePNG(w, cellSize); err != nil {
+			log.Printf("Stream Failure: %v", err)
+		}
+	default:
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+	}
+}
+
 func HandleRenderMaze(w http.ResponseWriter, r *http.Request) {
     w.Header().Set("Access-Control-Allow-Origin", "*")
     if r.Method == http.MethodOptions { return }
@@ -73,6 +448,10 @@ func HandleRenderMaze(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "Invalid data", http.StatusBadRequest)
         return
     }
+    if err := m.Validate(); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
 
     w.Header().Set("Content-Type", "image/png")
     m.RenderToWriter(w, 10) 
@@ -94,26 +473,80 @@ func HandleSolveMaze(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	if err := payload.Maze.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	var visited [][2]int
-	var path [][2]int
-
-	switch payload.Algorithm {
-	case "astar":
-		visited, path = payload.Maze.SolveAStar()
-	case "bfs":
-		visited, path = payload.Maze.SolveBFS()
-	case "greedy":
-    	visited, path = payload.Maze.SolveGreedy()
-	default:
+	solver, ok := maze.LookupSolver(payload.Algorithm)
+	if !ok {
 		http.Error(w, "Unsupported algorithm", http.StatusBadRequest)
 		return
 	}
+	visited, path := solver.Solve(&payload.Maze)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"visited": visited,
 		"path":    path,
+		"cost":    payload.Maze.PathCost(path),
+	})
+}
+
+// commonOptions are generate parameters that apply to every algorithm.
+var commonOptions = []maze.Param{
+	{Name: "seed", Type: "int", Description: "Seed that reproduces the same maze; random when omitted."},
+	{Name: "trace", Type: "int", Default: "0", Min: maze.Bound(0), Max: maze.Bound(1), Description: "Set to 1 to return the ordered generation steps for playback."},
+	{Name: "braid", Type: "float", Default: "0", Min: maze.Bound(0), Max: maze.Bound(1), Description: "Fraction of dead ends to remove, adding loops."},
+	{Name: "topology", Type: "string", Default: "square", Description: "Cell shape: square, hex or polar. Polar mazes have rows rings and ignore cols. Image and division generators need square cells."},
+	{Name: "levels", Type: "int", Default: "1", Min: maze.Bound(1), Max: maze.Bound(maze.MaxLevels), Description: "Floors stacked in the maze and joined by stairs. Rows of the grid and of start, end and paths run across floors, so row r is on floor r / rows."},
+	{Name: "wrap", Type: "string", Description: "Join opposite edges: cylinder joins left and right, torus also joins top and bottom. Square and hex grids only."},
+	{Name: "min_complexity", Type: "float", Min: maze.Bound(0), Description: "Lowest complexity score to accept. The server tries successive seeds until a maze lands in every requested band."},
+	{Name: "max_complexity", Type: "float", Min: maze.Bound(0), Description: "Highest complexity score to accept."},
+	{Name: "min_solution", Type: "int", Min: maze.Bound(0), Description: "Fewest cells on the shortest solution, both ends included."},
+	{Name: "max_solution", Type: "int", Min: maze.Bound(0), Description: "Most cells on the shortest solution."},
+	{Name: "min_dead_ends", Type: "int", Min: maze.Bound(0), Description: "Fewest dead ends to accept."},
+	{Name: "max_dead_ends", Type: "int", Min: maze.Bound(0), Description: "Most dead ends to accept."},
+	{Name: "budget_ms", Type: "int", Default: "2000", Min: maze.Bound(1), Max: maze.Bound(10000), Description: "Time allowed to search for a maze in the difficulty bands before giving up with DIFFICULTY_NOT_MET."},
+	{Name: "one_way", Type: "float", Default: "0", Min: maze.Bound(0), Max: maze.Bound(1), Description: "Fraction of passages to make one-way. The end always stays reachable from the start."},
+	{Name: "portals", Type: "int", Default: "0", Min: maze.Bound(0), Max: maze.Bound(maze.MaxPortals), Description: "Portal pairs to place, each joining two cells far apart along the passages. Either end leads to the other in one move."},
+	{Name: "keys", Type: "int", Default: "0", Min: maze.Bound(0), Max: maze.Bound(maze.MaxKeys), Description: "Locked doors to place along the solution, each with a key hidden before it that must be collected in order. Fewer are placed if the route has no cells that block it."},
+	{Name: "placement", Type: "string", Default: "random", Description: "Where the entrance and exit go: random border cells far apart, farthest (the two border cells farthest apart along the passages), min_path (random border cells at least min_path moves apart), interior (start inside, exit farthest from it) or corners."},
+	{Name: "min_path", Type: "int", Min: maze.Bound(0), Description: "Fewest moves between entrance and exit for min_path placement; defaults to half of rows plus cols."},
+	{Name: "terrain", Type: "string", Description: "Cell costs for the cost-aware solvers: noise for random patches of mud and water, or image to take them from terrain_image. Roads cost 1, mud 3 and water 5."},
+	{Name: "terrain_image", Type: "file", Description: "Image for image terrain; darker areas cost more to cross. Square and hex grids only."},
+	{Name: "mask", Type: "file", Description: "Silhouette whose dark, opaque area becomes the maze's shape."},
+	{Name: "mask_cells", Type: "string", Description: "JSON list of [row, col] cells to leave out of the maze."},
+}
+
+// HandleListAlgorithms describes the available generators and solvers so
+// the frontend can build its controls from the server.
+func HandleListAlgorithms(w http.ResponseWriter, r *http.Request) {
+	type algorithm struct {
+		Name        string       `json:"name"`
+		Description string       `json:"description"`
+		Params      []maze.Param `json:"params"`
+	}
+
+	generators := []algorithm{}
+	for _, g := range maze.Generators() {
+		params := g.Params()
+		if params == nil {
+			params = []maze.Param{}
+		}
+		generators = append(generators, algorithm{Name: g.Name(), Description: g.Description(), Params: params})
+	}
+
+	solvers := []algorithm{}
+	for _, s := range maze.Solvers() {
+		solvers = append(solvers, algorithm{Name: s.Name, Description: s.Description, Params: []maze.Param{}})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"generators": generators,
+		"solvers":    solvers,
+		"options":    commonOptions,
 	})
 }
 
@@ -128,21 +561,57 @@ func HandleGetMaze(w http.ResponseWriter, r *http.Request) {
 	var savedWeights map[string]int
 	json.Unmarshal([]byte(m.WeightsJSON), &savedWeights)
 
-	reconstructed := maze.NewMaze(m.Rows, m.Cols)
-	reconstructed.GenerateImageMaze(savedWeights)
-
-	for r := 0; r < m.Rows; r++ {
-		for c := 0; c < m.Cols; c++ {
-			if v, ok := savedWeights[fmt.Sprintf("%d-%d-top", r, c)]; ok { reconstructed.Grid[r][c].WallWeights[0] = v }
-			if v, ok := savedWeights[fmt.Sprintf("%d-%d-right", r, c)]; ok { reconstructed.Grid[r][c].WallWeights[1] = v }
-			if v, ok := savedWeights[fmt.Sprintf("%d-%d-bottom", r, c)]; ok { reconstructed.Grid[r][c].WallWeights[2] = v }
-			if v, ok := savedWeights[fmt.Sprintf("%d-%d-left", r, c)]; ok { reconstructed.Grid[r][c].WallWeights[3] = v }
+	// mazes saved before levels existed have none recorded
+	reconstructed, err := maze.NewMazeWithLevels(m.Rows, m.Cols, max(m.Levels, 1), m.Topology)
+	if err != nil {
+		http.Error(w, "CORRUPT_MAZE_DATA", http.StatusInternalServerError)
+		return
+	}
+	if err := reconstructed.SetWrap(m.Wrap); err != nil {
+		http.Error(w, "CORRUPT_MAZE_DATA", http.StatusInternalServerError)
+		return
+	}
+	if m.WallsData != "" {
+		if err := reconstructed.DecodeWalls(m.WallsData); err != nil {
+			http.Error(w, "CORRUPT_MAZE_DATA", http.StatusInternalServerError)
+			return
 		}
+	} else {
+		// Mazes saved before the wall bitfield existed only carry weights,
+		// so recover the walls from them and backfill the row.
+		reconstructed.WallsFromWeights(savedWeights)
+		db.DB.Model(&m).Update("walls_data", reconstructed.EncodeWalls())
 	}
-
+	if m.MaskData != "" {
+		mask, err := reconstructed.DecodeMask(m.MaskData)
+		if err == nil {
+			err = reconstructed.SetMask(mask)
+		}
+		if err != nil {
+			http.Error(w, "CORRUPT_MAZE_DATA", http.StatusInternalServerError)
+			return
+		}
+	}
+	if m.FeaturesData != "" {
+		if err := reconstructed.DecodeFeatures(m.FeaturesData); err != nil {
+			http.Error(w, "CORRUPT_MAZE_DATA", http.StatusInternalServerError)
+			return
+		}
+	}
+	if m.PortalsData != "" {
+		if err := reconstructed.DecodePortals(m.PortalsData); err != nil {
+			http.Error(w, "CORRUPT_MAZE_DATA", http.StatusInternalServerError)
+			return
+		}
+	}
+	reconstructed.ApplyWeights(savedWeights)
 	reconstructed.SetManualStartEnd(m.StartRow, m.StartCol, m.EndRow, m.EndCol)
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id": m.ID, "rows": m.Rows, "cols": m.Cols, "grid": reconstructed.Grid,
+		"id": m.ID, "rows": m.Rows, "cols": m.Cols, "seed": m.Seed, "algorithm": m.Algorithm,
+		"topology": reconstructed.Topology, "levels": reconstructed.Levels,
+		"wrap": reconstructed.Wrap, "placement": m.Placement, "grid": reconstructed.Grid,
+		"portals": reconstructed.Portals,
 		"start": [2]int{m.StartRow, m.StartCol}, "end": [2]int{m.EndRow, m.EndCol},
 	})
 }
@@ -0,0 +1,110 @@
+package maze
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+)
+
+// BiasOptions skews the passages Kruskal's algorithm and the backtracker
+// carve, giving structure without an image to guide them.
+type BiasOptions struct {
+	// Horizontal is the preference, from 0 to 1, for passages that stay in
+	// their row over ones that leave it. 0.5 is neutral; higher values read
+	// as mostly horizontal, lower ones as mostly vertical.
+	Horizontal float64 `json:"horizontal"`
+	// River, from 0 to 1, is how strongly a passage keeps going the way it
+	// came, giving long straight corridors at high values.
+	River float64 `json:"river"`
+}
+
+// DefaultBiasOptions leaves the generators unbiased.
+var DefaultBiasOptions = BiasOptions{Horizontal: 0.5}
+
+// Validate checks that both options lie between 0 and 1.
+func (b BiasOptions) Validate() error {
+	if b.Horizontal < 0 || b.Horizontal > 1 {
+		return errors.New("bias must be between 0 and 1")
+	}
+	if b.River < 0 || b.River > 1 {
+		return errors.New("river must be between 0 and 1")
+	}
+	return nil
+}
+
+// sideWeight is how likely side is to be carved relative to a neutral
+// side, doubling for horizontal passages at full bias and vanishing for
+// vertical ones.
+func (b BiasOptions) sideWeight(m *Maze, r, c, side int) float64 {
+	if m.isHorizontal(r, c, side) {
+		return 2 * b.Horizontal
+	}
+	return 2 * (1 - b.Horizontal)
+}
+
+// isHorizontal reports whether the passage through side stays in the row
+// of (r, c): east and west on square and hex grids, around the ring on
+// polar ones.
+func (m *Maze) isHorizontal(r, c, side int) bool {
+	nr, _, ok := m.neighbor(r, c, side)
+	return ok && nr == r
+}
+
+// behind returns the cell whose side leads into (r, c), i.e. the previous
+// cell on a straight line running through (r, c) in that direction.
+func (m *Maze) behind(r, c, side int) (int, int, bool) {
+	for back := range m.Grid[r][c].Walls {
+		br, bc, ok := m.neighbor(r, c, back)
+		if !ok || !m.isActive(br, bc) {
+			continue
+		}
+		if nr, nc, _ := m.neighbor(br, bc, side); nr == r && nc == c {
+			return br, bc, true
+		}
+	}
+	return 0, 0, false
+}
+
+// noLine marks a wall whose river weight has not been drawn yet.
+const noLine = math.MaxUint8
+
+// biasedWallWeight draws a Kruskal weight for the wall through side of
+// (r, c). Lower weights are carved first, so favoured sides are scaled
+// down. With a river each weight leans towards that of the wall behind it
+// on the same line, recorded in lines, so runs of walls open together.
+func (m *Maze) biasedWallWeight(r, c, side int, bias BiasOptions, lines []uint8, rng *rand.Rand) int {
+	w := float64(rng.IntN(100))
+	if lines != nil {
+		sides := m.topo().Sides()
+		if br, bc, ok := m.behind(r, c, side); ok {
+			if prev := lines[m.index(br, bc)*sides+side]; prev != noLine {
+				w = bias.River*float64(prev) + (1-bias.River)*w
+			}
+		}
+		lines[m.index(r, c)*sides+side] = uint8(math.Round(w))
+	}
+	return int(math.Round(w * (2 - bias.sideWeight(m, r, c, side))))
+}
+
+// biasedOrder shuffles dirs so each side comes ahead of the rest in
+// proportion to its weight, and favoured sides tend to be tried first.
+func (m *Maze) biasedOrder(r, c int, dirs []uint8, bias BiasOptions, rng *rand.Rand) {
+	weight := func(side uint8) float64 {
+		// keep every side possible, just unlikely, at full bias
+		return max(bias.sideWeight(m, r, c, int(side)), 1e-6)
+	}
+	for i := range dirs {
+		total := 0.0
+		for _, side := range dirs[i:] {
+			total += weight(side)
+		}
+		x := rng.Float64() * total
+		j := i
+		for ; j < len(dirs)-1; j++ {
+			if x -= weight(dirs[j]); x < 0 {
+				break
+			}
+		}
+		dirs[i], dirs[j] = dirs[j], dirs[i]
+	}
+}
@@ -0,0 +1,72 @@
+package maze
+
+import "math/rand/v2"
+
+// Braid removes dead ends by knocking out one of their walls, turning a
+// perfect maze into one with loops and multiple routes. fraction is the
+// share of dead ends to remove, from 0 to 1. It returns the number of
+// loops introduced.
+func (m *Maze) Braid(fraction float64) int {
+	return m.braid(fraction, m.Rand())
+}
+
+func (m *Maze) braid(fraction float64, rng *rand.Rand) int {
+	isDeadEnd := func(r, c int) bool {
+		open := 0
+		for _, isWall := range m.Grid[r][c].Walls {
+			if !isWall {
+				open++
+			}
+		}
+		return open == 1
+	}
+
+	var deadEnds []Point
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if isDeadEnd(r, c) {
+				deadEnds = append(deadEnds, Point{r, c})
+			}
+		}
+	}
+	rng.Shuffle(len(deadEnds), func(i, j int) {
+		deadEnds[i], deadEnds[j] = deadEnds[j], deadEnds[i]
+	})
+
+	loops := 0
+	var walled, walledDeadEnds []Point
+	for _, p := range deadEnds {
+		r, c := p[0], p[1]
+		// an earlier removal may already have opened this one up
+		if !isDeadEnd(r, c) || rng.Float64() >= fraction {
+			continue
+		}
+
+		walled, walledDeadEnds = walled[:0], walledDeadEnds[:0]
+		for i := range m.Grid[r][c].Walls {
+			nr, nc, _ := m.neighbor(r, c, i)
+			// a crossing's walls belong to the passages over and under it
+			if !m.isActive(nr, nc) || !m.Grid[r][c].Walls[i] || m.Grid[nr][nc].Tunnel {
+				continue
+			}
+			walled = append(walled, Point{nr, nc})
+			if isDeadEnd(nr, nc) {
+				walledDeadEnds = append(walledDeadEnds, Point{nr, nc})
+			}
+		}
+
+		// joining two dead ends clears both with a single wall
+		candidates := walledDeadEnds
+		if len(candidates) == 0 {
+			candidates = walled
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		next := candidates[rng.IntN(len(candidates))]
+		m.RemoveWalls(r, c, next[0], next[1])
+		loops++
+	}
+	return loops
+}
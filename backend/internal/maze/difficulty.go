@@ -0,0 +1,104 @@
+package maze
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Budgets for searching seeds until a maze lands in a difficulty band.
+const (
+	DefaultSearchBudget = 2 * time.Second
+	MaxSearchBudget     = 10 * time.Second
+)
+
+// ErrDifficultyNotMet is returned when no maze inside the difficulty band
+// turned up before the search ran out of time.
+var ErrDifficultyNotMet = errors.New("no maze met the difficulty in time")
+
+// Difficulty is a band a finished maze must fall within. Zero bounds are
+// left open, so the zero Difficulty accepts every maze.
+type Difficulty struct {
+	MinComplexity float64
+	MaxComplexity float64
+	MinSolution   int // cells on the shortest path, both ends included
+	MaxSolution   int
+	MinDeadEnds   int
+	MaxDeadEnds   int
+}
+
+// IsZero reports whether d leaves every bound open.
+func (d Difficulty) IsZero() bool {
+	return d == Difficulty{}
+}
+
+// Validate checks that every bound is non-negative and that no band is
+// empty.
+func (d Difficulty) Validate() error {
+	if d.MinComplexity < 0 || d.MaxComplexity < 0 || d.MinSolution < 0 ||
+		d.MaxSolution < 0 || d.MinDeadEnds < 0 || d.MaxDeadEnds < 0 {
+		return errors.New("difficulty bounds must not be negative")
+	}
+	if d.MaxComplexity > 0 && d.MinComplexity > d.MaxComplexity {
+		return errors.New("min_complexity is above max_complexity")
+	}
+	if d.MaxSolution > 0 && d.MinSolution > d.MaxSolution {
+		return errors.New("min_solution is above max_solution")
+	}
+	if d.MaxDeadEnds > 0 && d.MinDeadEnds > d.MaxDeadEnds {
+		return errors.New("min_dead_ends is above max_dead_ends")
+	}
+	return nil
+}
+
+// Met reports whether m, with its start and end placed, falls inside d.
+// The maze is only solved when a solution bound asks for it.
+func (d Difficulty) Met(m *Maze) bool {
+	stats := m.CalculateStats()
+	if stats.Complexity < d.MinComplexity || (d.MaxComplexity > 0 && stats.Complexity > d.MaxComplexity) {
+		return false
+	}
+	if stats.DeadEnds < d.MinDeadEnds || (d.MaxDeadEnds > 0 && stats.DeadEnds > d.MaxDeadEnds) {
+		return false
+	}
+	if d.MinSolution == 0 && d.MaxSolution == 0 {
+		return true
+	}
+	_, path := m.SolveBFS()
+	return len(path) > 0 && len(path) >= d.MinSolution && (d.MaxSolution == 0 || len(path) <= d.MaxSolution)
+}
+
+// SearchDifficulty builds mazes from successive seeds, starting at seed,
+// until one falls inside d, and returns it. build must return a finished
+// maze for the seed it is given, so the returned maze's Seed reproduces it.
+// The search gives up with ErrDifficultyNotMet once ctx's deadline passes,
+// or with ctx's error if it is cancelled first, and build is handed ctx so
+// a slow build stops at the same point.
+func SearchDifficulty(ctx context.Context, d Difficulty, seed int64, build func(ctx context.Context, seed int64) (*Maze, error)) (*Maze, error) {
+	for attempt := 1; ; attempt++ {
+		m, err := build(ctx, seed)
+		if err != nil && ctx.Err() != nil {
+			return nil, searchStopped(ctx, attempt)
+		} else if err != nil {
+			return nil, err
+		}
+		if d.Met(m) {
+			return m, nil
+		}
+		if ctx.Err() != nil {
+			return nil, searchStopped(ctx, attempt)
+		}
+		seed++
+	}
+}
+
+// searchStopped explains why a search ended early: running out of time
+// means no maze met the difficulty, while a cancelled search, such as one
+// whose client went away, reports the cancellation itself.
+func searchStopped(ctx context.Context, attempts int) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: tried %d seeds", ErrDifficultyNotMet, attempts)
+	}
+	return ctx.Err()
+}
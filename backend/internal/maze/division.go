@@ -0,0 +1,90 @@
+package maze
+
+import "math/rand/v2"
+
+// DivisionOptions tunes the recursive division generator.
+type DivisionOptions struct {
+	// MinChamber is the smallest width or height a split may leave a
+	// chamber with. Values above 1 leave open rooms in the maze.
+	MinChamber int `json:"min_chamber"`
+	// HorizontalBias is the chance, from 0 to 1, of splitting a square
+	// chamber with a horizontal wall. 0.5 is neutral; higher values give
+	// long horizontal corridors.
+	HorizontalBias float64 `json:"horizontal_bias"`
+}
+
+// DefaultDivisionOptions produces a perfect maze with no directional bias.
+var DefaultDivisionOptions = DivisionOptions{MinChamber: 1, HorizontalBias: 0.5}
+
+// GenerateDivision builds a maze by starting from an open field and
+// repeatedly splitting chambers with a wall that has a single gap.
+func (m *Maze) GenerateDivision(opts DivisionOptions) {
+	m.generateDivision(opts, m.Rand())
+}
+
+func (m *Maze) generateDivision(opts DivisionOptions, rng *rand.Rand) {
+	m.initializeWallWeights(255)
+	if opts.MinChamber < 1 {
+		opts.MinChamber = 1
+	}
+	opts.HorizontalBias = min(max(opts.HorizontalBias, 0), 1)
+
+	// clear every interior wall, leaving only the outer boundary
+	for r := range m.Grid {
+		for c := 0; c < m.Cols; c++ {
+			for i, d := range gridDirs {
+				m.Grid[r][c].Walls[i] = m.Grid[r][c].Masked || !m.isActive(r+d[0], c+d[1])
+			}
+		}
+	}
+	m.trace(TraceClear)
+
+	m.divide(0, 0, m.Rows, m.Cols, opts, rng)
+
+	// a mask can swallow the only gap in a wall, cutting chambers off
+	if m.HasMask() {
+		m.connectRegions(rng)
+	}
+}
+
+// divide splits the chamber with top-left corner (r, c) and size h x w.
+func (m *Maze) divide(r, c, h, w int, opts DivisionOptions, rng *rand.Rand) {
+	canSplitH := h >= 2*opts.MinChamber
+	canSplitV := w >= 2*opts.MinChamber
+	if !canSplitH && !canSplitV {
+		return
+	}
+
+	horizontal := canSplitH
+	if canSplitH && canSplitV {
+		// favour cutting across the longer side, skewed by the bias
+		hWeight := opts.HorizontalBias * float64(h)
+		vWeight := (1 - opts.HorizontalBias) * float64(w)
+		horizontal = hWeight+vWeight > 0 && rng.Float64()*(hWeight+vWeight) < hWeight
+	}
+
+	if horizontal {
+		// the wall runs along the top edge of row y
+		y := r + opts.MinChamber + rng.IntN(h-2*opts.MinChamber+1)
+		gap := c + rng.IntN(w)
+		for x := c; x < c+w; x++ {
+			if x != gap {
+				m.AddWalls(y-1, x, y, x)
+			}
+		}
+		m.divide(r, c, y-r, w, opts, rng)
+		m.divide(y, c, r+h-y, w, opts, rng)
+		return
+	}
+
+	// the wall runs along the left edge of column x
+	x := c + opts.MinChamber + rng.IntN(w-2*opts.MinChamber+1)
+	gap := r + rng.IntN(h)
+	for y := r; y < r+h; y++ {
+		if y != gap {
+			m.AddWalls(y, x-1, y, x)
+		}
+	}
+	m.divide(r, c, h, x-c, opts, rng)
+	m.divide(r, x, h, c+w-x, opts, rng)
+}
@@ -0,0 +1,226 @@
+package maze
+
+import (
+	"errors"
+	"math/rand/v2"
+)
+
+// DungeonOptions tunes the rooms-and-corridors generator.
+type DungeonOptions struct {
+	// Rooms is how many rooms to try to place. Fewer fit on small or
+	// crowded grids.
+	Rooms int `json:"rooms"`
+	// MinRoom and MaxRoom bound the width and height of a room in cells.
+	MinRoom int `json:"min_room"`
+	MaxRoom int `json:"max_room"`
+	// Doors is the most doors a room may have. Every room has at least one,
+	// and rooms with more add loops to the dungeon.
+	Doors int `json:"doors"`
+	// Prune, from 0 to 1, is the chance that each dead-end corridor is
+	// filled back in to solid rock, leaving only the corridors that lead
+	// somewhere at 1.
+	Prune float64 `json:"prune"`
+}
+
+// DefaultDungeonOptions places a handful of medium rooms with up to two
+// doors each and keeps every corridor.
+var DefaultDungeonOptions = DungeonOptions{Rooms: 8, MinRoom: 3, MaxRoom: 6, Doors: 2}
+
+// Validate checks that the room count, sizes and doors are positive and
+// Prune lies between 0 and 1.
+func (o DungeonOptions) Validate() error {
+	if o.Rooms < 0 {
+		return errors.New("rooms must not be negative")
+	}
+	if o.MinRoom < 1 || o.MaxRoom < o.MinRoom {
+		return errors.New("room sizes must satisfy 1 <= min_room <= max_room")
+	}
+	if o.Doors < 1 {
+		return errors.New("doors must be at least 1")
+	}
+	if o.Prune < 0 || o.Prune > 1 {
+		return errors.New("prune must be between 0 and 1")
+	}
+	return nil
+}
+
+// GenerateDungeon builds a dungeon: open rectangular rooms set in a
+// perfect maze of corridors, each room joined to it by one or more doors.
+// Pruned corridor cells are masked out as solid rock.
+func (m *Maze) GenerateDungeon(opts DungeonOptions) {
+	m.generateDungeon(opts, m.Rand())
+}
+
+func (m *Maze) generateDungeon(opts DungeonOptions, rng *rand.Rand) {
+	m.initializeWallWeights(255)
+
+	// room[i] is one more than the index of the room holding cell i, or 0
+	// for corridor cells
+	room := make([]int, m.cellCount())
+	rooms := m.placeRooms(room, opts, rng)
+	dsu := NewDSU(m.cellCount())
+
+	// open up each room
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			id := m.index(r, c)
+			for _, side := range []int{1, 2} {
+				nr, nc, _ := m.neighbor(r, c, side)
+				if room[id] > 0 && m.isActive(nr, nc) && room[m.index(nr, nc)] == room[id] {
+					m.RemoveWalls(r, c, nr, nc)
+					dsu.Union(id, m.index(nr, nc))
+				}
+			}
+		}
+	}
+
+	// fill the space between them with Kruskal's algorithm, which leaves a
+	// perfect maze in every pocket of corridor
+	var corridors []Wall
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if !m.isActive(r, c) || room[m.index(r, c)] > 0 {
+				continue
+			}
+			for _, side := range []int{1, 2} {
+				nr, nc, _ := m.neighbor(r, c, side)
+				if m.isActive(nr, nc) && room[m.index(nr, nc)] == 0 {
+					corridors = append(corridors, Wall{R1: r, C1: c, R2: nr, C2: nc})
+				}
+			}
+		}
+	}
+	rng.Shuffle(len(corridors), func(i, j int) {
+		corridors[i], corridors[j] = corridors[j], corridors[i]
+	})
+	for _, w := range corridors {
+		id1, id2 := m.index(w.R1, w.C1), m.index(w.R2, w.C2)
+		if dsu.Find(id1) != dsu.Find(id2) {
+			m.RemoveWalls(w.R1, w.C1, w.R2, w.C2)
+			dsu.Union(id1, id2)
+		}
+	}
+
+	// doors first join every room and pocket into one dungeon, then rooms
+	// get extra doors up to a random count of their own
+	var doorways []Wall
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			id := m.index(r, c)
+			if room[id] == 0 || !m.isActive(r, c) {
+				continue
+			}
+			for side := range m.Grid[r][c].Walls {
+				nr, nc, _ := m.neighbor(r, c, side)
+				if m.isActive(nr, nc) && room[m.index(nr, nc)] != room[id] {
+					doorways = append(doorways, Wall{R1: r, C1: c, R2: nr, C2: nc})
+				}
+			}
+		}
+	}
+	rng.Shuffle(len(doorways), func(i, j int) {
+		doorways[i], doorways[j] = doorways[j], doorways[i]
+	})
+	doors := make([]int, rooms)
+	opened := make([]bool, len(doorways))
+	for i, w := range doorways {
+		id1, id2 := m.index(w.R1, w.C1), m.index(w.R2, w.C2)
+		if dsu.Find(id1) != dsu.Find(id2) {
+			m.RemoveWalls(w.R1, w.C1, w.R2, w.C2)
+			dsu.Union(id1, id2)
+			doors[room[id1]-1]++
+			opened[i] = true
+		}
+	}
+	want := make([]int, rooms)
+	for i := range want {
+		want[i] = 1 + rng.IntN(opts.Doors)
+	}
+	for i, w := range doorways {
+		if n := room[m.index(w.R1, w.C1)] - 1; !opened[i] && doors[n] < want[n] {
+			m.RemoveWalls(w.R1, w.C1, w.R2, w.C2)
+			doors[n]++
+		}
+	}
+
+	// a mask can cut a pocket of corridor off from every room
+	if m.HasMask() {
+		m.connectRegions(rng)
+	}
+	if rooms > 0 && opts.Prune > 0 {
+		m.pruneCorridors(room, opts.Prune, rng)
+	}
+}
+
+// placeRooms drops up to opts.Rooms rooms at random, each kept at least a
+// cell away from the others so corridors can run between them, and labels
+// their cells in room. It returns the number placed.
+func (m *Maze) placeRooms(room []int, opts DungeonOptions, rng *rand.Rand) int {
+	placed := 0
+	for attempt := 0; placed < opts.Rooms && attempt < 30*opts.Rooms; attempt++ {
+		h := min(opts.MinRoom+rng.IntN(opts.MaxRoom-opts.MinRoom+1), m.Rows)
+		w := min(opts.MinRoom+rng.IntN(opts.MaxRoom-opts.MinRoom+1), m.Cols)
+		top, left := rng.IntN(m.Rows-h+1), rng.IntN(m.Cols-w+1)
+
+		fits := true
+		for r := top - 1; r <= top+h && fits; r++ {
+			for c := left - 1; c <= left+w && fits; c++ {
+				inside := r >= top && r < top+h && c >= left && c < left+w
+				if inside && !m.isActive(r, c) {
+					fits = false
+				} else if m.inBounds(r, c) && room[m.index(r, c)] > 0 {
+					fits = false
+				}
+			}
+		}
+		if !fits {
+			continue
+		}
+
+		placed++
+		for r := top; r < top+h; r++ {
+			for c := left; c < left+w; c++ {
+				room[m.index(r, c)] = placed
+			}
+		}
+	}
+	return placed
+}
+
+// pruneCorridors fills in each dead-end corridor with probability prune,
+// retreating cell by cell until it reaches a junction or a room, and masks
+// the filled cells out as rock.
+func (m *Maze) pruneCorridors(room []int, prune float64, rng *rand.Rand) {
+	// openSide returns the only open passage out of a dead end, or -1
+	openSide := func(r, c int) int {
+		side := -1
+		for s, isWall := range m.Grid[r][c].Walls {
+			if isWall {
+				continue
+			}
+			if nr, nc, _ := m.neighbor(r, c, s); !m.isActive(nr, nc) || side >= 0 {
+				return -1
+			}
+			side = s
+		}
+		return side
+	}
+
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if !m.isActive(r, c) || room[m.index(r, c)] > 0 || openSide(r, c) < 0 || rng.Float64() >= prune {
+				continue
+			}
+			for cr, cc := r, c; m.isActive(cr, cc) && room[m.index(cr, cc)] == 0; {
+				side := openSide(cr, cc)
+				if side < 0 {
+					break
+				}
+				nr, nc, _ := m.neighbor(cr, cc, side)
+				m.AddWalls(cr, cc, nr, nc)
+				m.Grid[cr][cc].Masked = true
+				cr, cc = nr, nc
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package maze
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDungeonEntrancesAvoidEnclosedRock(t *testing.T) {
+	for _, strategy := range []string{PlaceRandom, PlaceFarthest, PlaceInterior} {
+		for seed := range int64(20) {
+			m := NewMaze(30, 30)
+			m.SetSeed(seed)
+			gen, err := NewGenerator("dungeon", Params{"prune": "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := gen.Generate(context.Background(), m, m.Rand()); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.SetStartEnd(Placement{Strategy: strategy}); err != nil {
+				t.Fatal(err)
+			}
+
+			outside := m.exterior()
+			for _, p := range [][2]int{m.Start, m.End} {
+				for side, isWall := range m.Grid[p[0]][p[1]].Walls {
+					nr, nc, _ := m.neighbor(p[0], p[1], side)
+					if !isWall && m.inBounds(nr, nc) && m.Grid[nr][nc].Masked && !outside[m.index(nr, nc)] {
+						t.Errorf("%s seed %d: entrance at %v opens into enclosed rock", strategy, seed, p)
+					}
+				}
+			}
+			if _, path := m.SolveBFS(); len(path) == 0 {
+				t.Errorf("%s seed %d: no solution", strategy, seed)
+			}
+		}
+	}
+}
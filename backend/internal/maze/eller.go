@@ -0,0 +1,172 @@
+package maze
+
+import "math/rand/v2"
+
+// EllerStream generates a maze with Eller's algorithm one row at a time.
+// Only the current row and its set labels are kept in memory, so the
+// number of rows is effectively unbounded.
+//
+// The entrance is the top of the first cell and the exit is the bottom of
+// the last cell.
+type EllerStream struct {
+	Rows int
+	Cols int
+	Seed int64
+
+	rng  *rand.Rand
+	row  int
+	sets []int  // set label of each cell in the upcoming row, -1 if unassigned
+	up   []bool // whether each cell in the upcoming row has a passage upwards
+
+	// scratch buffers reused for every row
+	cells   []Cell
+	parent  []int
+	relabel []int
+	count   []int
+	pick    []int
+	hasDown []bool
+	down    []bool
+}
+
+// NewEllerStream prepares a streaming generator for a rows x cols maze.
+func NewEllerStream(rows, cols int, seed int64) *EllerStream {
+	e := &EllerStream{
+		Rows:    rows,
+		Cols:    cols,
+		Seed:    seed,
+		rng:     rand.New(rand.NewPCG(uint64(seed), 0)),
+		sets:    make([]int, cols),
+		up:      make([]bool, cols),
+		cells:   make([]Cell, cols),
+		parent:  make([]int, cols),
+		relabel: make([]int, cols),
+		count:   make([]int, cols),
+		pick:    make([]int, cols),
+		hasDown: make([]bool, cols),
+		down:    make([]bool, cols),
+	}
+	for c := range e.sets {
+		e.sets[c] = -1
+	}
+
+	walls := make([]bool, cols*4)
+	weights := make([]int, cols*4)
+	for i := range weights {
+		weights[i] = 255
+	}
+	for c := range e.cells {
+		e.cells[c].Walls = walls[c*4 : c*4+4 : c*4+4]
+		e.cells[c].WallWeights = weights[c*4 : c*4+4 : c*4+4]
+	}
+	return e
+}
+
+func (e *EllerStream) find(i int) int {
+	for e.parent[i] != i {
+		e.parent[i] = e.parent[e.parent[i]]
+		i = e.parent[i]
+	}
+	return i
+}
+
+// Next produces the next row of the maze. The returned slice is reused by
+// the following call, so callers must copy it if they need to keep it.
+func (e *EllerStream) Next() ([]Cell, bool) {
+	if e.row >= e.Rows || e.Cols == 0 {
+		return nil, false
+	}
+	r, cols := e.row, e.Cols
+	isLast := r == e.Rows-1
+	rng := e.rng
+
+	// labels stay below cols, so fresh sets take the lowest unused label
+	inUse := e.count
+	for c := range inUse {
+		inUse[c] = 0
+	}
+	for _, s := range e.sets {
+		if s >= 0 {
+			inUse[s] = 1
+		}
+	}
+	fresh := 0
+	for c := range e.sets {
+		if e.sets[c] < 0 {
+			for inUse[fresh] != 0 {
+				fresh++
+			}
+			e.sets[c] = fresh
+			inUse[fresh] = 1
+		}
+		e.parent[c] = c
+	}
+
+	for c := range e.cells {
+		cell := &e.cells[c]
+		cell.Row, cell.Col = r, c
+		cell.Walls[0], cell.Walls[1], cell.Walls[2], cell.Walls[3] = !e.up[c], true, true, true
+	}
+	if r == 0 {
+		e.cells[0].Walls[0] = false
+	}
+
+	// join neighbouring cells from different sets, always on the last row
+	for c := 0; c < cols-1; c++ {
+		a, b := e.find(e.sets[c]), e.find(e.sets[c+1])
+		if a != b && (isLast || rng.IntN(2) == 0) {
+			e.parent[a] = b
+			e.cells[c].Walls[1] = false
+			e.cells[c+1].Walls[3] = false
+		}
+	}
+
+	if isLast {
+		e.cells[cols-1].Walls[2] = false
+		e.row++
+		return e.cells, true
+	}
+
+	// every set needs at least one passage down; reservoir sampling picks
+	// a fallback cell for sets that did not get one by chance
+	for c := range e.count {
+		e.count[c] = 0
+		e.hasDown[c] = false
+	}
+	for c := 0; c < cols; c++ {
+		root := e.find(e.sets[c])
+		e.down[c] = rng.IntN(2) == 0
+		e.hasDown[root] = e.hasDown[root] || e.down[c]
+		e.count[root]++
+		if rng.IntN(e.count[root]) == 0 {
+			e.pick[root] = c
+		}
+	}
+	for c := 0; c < cols; c++ {
+		root := e.find(e.sets[c])
+		if !e.hasDown[root] && e.pick[root] == c {
+			e.down[c] = true
+		}
+	}
+
+	for c := range e.relabel {
+		e.relabel[c] = -1
+	}
+	next := 0
+	for c := 0; c < cols; c++ {
+		e.up[c] = e.down[c]
+		if !e.down[c] {
+			e.sets[c] = -1
+			continue
+		}
+		e.cells[c].Walls[2] = false
+		root := e.find(e.sets[c])
+		if e.relabel[root] < 0 {
+			e.relabel[root] = next
+			next++
+		}
+		e.sets[c] = e.relabel[root]
+	}
+
+	e.row++
+	return e.cells, true
+}
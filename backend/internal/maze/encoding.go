@@ -0,0 +1,168 @@
+package maze
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// EncodeWalls packs the wall topology of the grid into a compact string.
+// The first byte records the number of walls per cell, followed by one bit
+// per wall in row-major order, all base64 encoded.
+func (m *Maze) EncodeWalls() string {
+	sides := m.topo().Sides()
+	buf := make([]byte, 1+(m.cellCount()*sides+7)/8)
+	buf[0] = byte(sides)
+
+	bit := 0
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			for _, isWall := range m.Grid[r][c].Walls {
+				if isWall {
+					buf[1+bit/8] |= 1 << (bit % 8)
+				}
+				bit++
+			}
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// DecodeWalls restores a wall topology produced by EncodeWalls onto a grid
+// of matching dimensions.
+func (m *Maze) DecodeWalls(data string) error {
+	buf, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("decode walls: %w", err)
+	}
+
+	sides := m.topo().Sides()
+	if len(buf) == 0 || int(buf[0]) != sides {
+		return fmt.Errorf("decode walls: unsupported wall layout")
+	}
+	if len(buf) != 1+(m.cellCount()*sides+7)/8 {
+		return fmt.Errorf("decode walls: data does not match a %dx%d grid", m.Rows, m.Cols)
+	}
+
+	bit := 0
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			for i := range m.Grid[r][c].Walls {
+				m.Grid[r][c].Walls[i] = buf[1+bit/8]&(1<<(bit%8)) != 0
+				bit++
+			}
+		}
+	}
+	m.markTunnels()
+	return nil
+}
+
+// EncodeMask packs the masked cells into a base64 bitfield, one bit per
+// cell in row-major order. It returns "" when the maze has no mask.
+func (m *Maze) EncodeMask() string {
+	if !m.HasMask() {
+		return ""
+	}
+
+	buf := make([]byte, (m.cellCount()+7)/8)
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if bit := m.index(r, c); m.Grid[r][c].Masked {
+				buf[bit/8] |= 1 << (bit % 8)
+			}
+		}
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// DecodeMask unpacks a bitfield produced by EncodeMask into a mask
+// matching the maze's grid, ready for SetMask.
+func (m *Maze) DecodeMask(data string) ([][]bool, error) {
+	buf, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode mask: %w", err)
+	}
+	if len(buf) != (m.cellCount()+7)/8 {
+		return nil, fmt.Errorf("decode mask: data does not match a %dx%d grid", m.Rows, m.Cols)
+	}
+
+	mask := m.emptyMask()
+	for r := range mask {
+		for c := range mask[r] {
+			bit := m.index(r, c)
+			mask[r][c] = buf[bit/8]&(1<<(bit%8)) != 0
+		}
+	}
+	return mask, nil
+}
+
+// weightKey returns the key SyncGridToWeights uses for a cell's wall.
+// Interior walls are shared and keyed by the later cell in row-major
+// order, so on square grids bottom and right walls map onto the
+// neighbouring cell's top and left keys.
+func (m *Maze) weightKey(r, c, wallIdx int) string {
+	if !m.ownsWall(r, c, wallIdx) {
+		nr, nc, _ := m.neighbor(r, c, wallIdx)
+		r, c, wallIdx = nr, nc, m.sideTowards(nr, nc, r, c)
+	}
+	return m.sideKey(r, c, wallIdx)
+}
+
+// sideKey returns the key of one side of a wall as seen from cell (r, c).
+// It differs from weightKey only for the side that does not own the wall.
+func (m *Maze) sideKey(r, c, wallIdx int) string {
+	return fmt.Sprintf("%d-%d-%s", r, c, m.topo().SideNames()[wallIdx])
+}
+
+// splitWall reports whether the two sides of a wall disagree on whether it
+// is closed, as they do where a weave tunnel passes under a crossing. Each
+// side of such a wall keeps its own weight under its sideKey.
+func (m *Maze) splitWall(r, c, wallIdx int) bool {
+	nr, nc, ok := m.neighbor(r, c, wallIdx)
+	if !ok {
+		return false
+	}
+	back := m.sideTowards(nr, nc, r, c)
+	return back >= 0 && m.Grid[nr][nc].Walls[back] != m.Grid[r][c].Walls[wallIdx]
+}
+
+// ownsWall reports whether (r, c) holds the weight key for one of its
+// walls: walls on the grid edge, and walls shared with an earlier cell.
+func (m *Maze) ownsWall(r, c, wallIdx int) bool {
+	nr, nc, ok := m.neighbor(r, c, wallIdx)
+	return !ok || m.index(nr, nc) < m.index(r, c)
+}
+
+// ApplyWeights copies persisted wall shading from a weights map back onto
+// the grid without touching the wall topology.
+func (m *Maze) ApplyWeights(weights map[string]int) {
+	m.Weights = weights
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			for i := range m.Grid[r][c].WallWeights {
+				key := m.weightKey(r, c, i)
+				if m.splitWall(r, c, i) {
+					key = m.sideKey(r, c, i)
+				}
+				if w, ok := weights[key]; ok {
+					m.Grid[r][c].WallWeights[i] = w
+				}
+			}
+		}
+	}
+}
+
+// WallsFromWeights rebuilds the wall topology from a weights map written by
+// SyncGridToWeights, where a weight of 0 marks an open passage. It is the
+// migration path for mazes saved before the wall bitfield was persisted.
+func (m *Maze) WallsFromWeights(weights map[string]int) {
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			for i := range m.Grid[r][c].Walls {
+				if w, ok := weights[m.weightKey(r, c, i)]; ok {
+					m.Grid[r][c].Walls[i] = w != 0
+				}
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package maze
 import (
 	"fmt"
 	"math/rand/v2"
+	"slices"
 	"sort"
 )
 
@@ -16,9 +17,9 @@ type Wall struct {
 // initializeWallWeights sets every wall weight in the grid to a specific value.
 // This is used to ensure solid colors for non-image-based generation modes.
 func (m *Maze) initializeWallWeights(val int) {
-	for r := 0; r < m.Rows; r++ {
-		for c := 0; c < m.Cols; c++ {
-			for i := 0; i < 4; i++ {
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			for i := range m.Grid[r][c].WallWeights {
 				m.Grid[r][c].WallWeights[i] = val
 			}
 		}
@@ -27,17 +28,31 @@ func (m *Maze) initializeWallWeights(val int) {
 
 // GenerateKruskal triggers a standard randomized Kruskal's generation.
 func (m *Maze) GenerateKruskal() {
-	m.initializeWallWeights(255)
-	m.generateWeightedKruskal(nil)
+	m.generateWeightedKruskal(nil, DefaultBiasOptions, m.Rand())
 }
 
 // GenerateImageMaze triggers a guided Kruskal's generation using weights
 // derived from an image.
 func (m *Maze) GenerateImageMaze(weights map[string]int) {
-    m.Weights = weights
+	m.Weights = weights
+	m.generateWeightedKruskal(weights, DefaultBiasOptions, m.Rand())
+}
+
+// setupWallWeights readies the wall weights for a generator: image weights
+// on the outer boundary when guided by edgeWeights, solid walls otherwise.
+func (m *Maze) setupWallWeights(edgeWeights map[string]int) {
+	if edgeWeights != nil {
+		m.applyBorderWeights(edgeWeights)
+	} else {
+		m.initializeWallWeights(255)
+	}
+}
 
-    for r := 0; r < m.Rows; r++ {
-        for c := 0; c < m.Cols; c++ {
+// applyBorderWeights copies image weights for the outer boundary walls,
+// which never take part in spanning tree generation.
+func (m *Maze) applyBorderWeights(weights map[string]int) {
+    for r := range m.Grid {
+        for c := range m.Grid[r] {
             if r == 0 {
                 if w, ok := weights[fmt.Sprintf("%d-%d-top", r, c)]; ok {
                     m.Grid[r][c].WallWeights[0] = w
@@ -60,33 +75,59 @@ func (m *Maze) GenerateImageMaze(weights map[string]int) {
             }
         }
     }
+}
+
+// wallWeight returns the image weight for a wall, falling back to a random
+// priority when the image left it unweighted.
+func wallWeight(edgeWeights map[string]int, key string, rng *rand.Rand) int {
+	if val, ok := edgeWeights[key]; ok {
+		return val
+	}
+	return rng.IntN(100)
+}
 
-	m.generateWeightedKruskal(weights)
+// recordWallWeight stores a wall's priority on both cells it separates so
+// image-guided mazes are shaded by the renderer.
+func (m *Maze) recordWallWeight(w Wall) {
+	m.Grid[w.R1][w.C1].WallWeights[m.sideTowards(w.R1, w.C1, w.R2, w.C2)] = w.Weight
+	m.Grid[w.R2][w.C2].WallWeights[m.sideTowards(w.R2, w.C2, w.R1, w.C1)] = w.Weight
 }
 
 // generateWeightedKruskal implements the core spanning tree logic.
-func (m *Maze) generateWeightedKruskal(edgeWeights map[string]int) {
-	dsu := NewDSU(m.Rows * m.Cols)
+// Walls without an image weight are ordered using rng, skewed by bias.
+func (m *Maze) generateWeightedKruskal(edgeWeights map[string]int, bias BiasOptions, rng *rand.Rand) {
+	m.setupWallWeights(edgeWeights)
+	dsu := NewDSU(m.cellCount())
 	var walls []Wall
 	isImageMode := edgeWeights != nil
 
-	for r := 0; r < m.Rows; r++ {
-		for c := 0; c < m.Cols; c++ {
-			if r < m.Rows-1 {
-				w := Wall{R1: r, C1: c, R2: r + 1, C2: c}
-				if val, ok := edgeWeights[fmt.Sprintf("%d-%d-top", r+1, c)]; ok {
-					w.Weight = val
-				} else {
-					w.Weight = rand.IntN(100) 
-				}
-				walls = append(walls, w)
+	// a river needs the weight of the wall behind each one on its line
+	var lines []uint8
+	if bias.River > 0 {
+		lines = make([]uint8, m.cellCount()*m.topo().Sides())
+		for i := range lines {
+			lines[i] = noLine
+		}
+	}
+
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if !m.isActive(r, c) {
+				continue
 			}
-			if c < m.Cols-1 {
-				w := Wall{R1: r, C1: c, R2: r, C2: c + 1}
-				if val, ok := edgeWeights[fmt.Sprintf("%d-%d-left", r, c+1)]; ok {
-					w.Weight = val
+			// each wall is listed once, from the earlier of its two cells;
+			// sides run last to first so square grids keep their seeds
+			for side := len(m.Grid[r][c].Walls) - 1; side >= 0; side-- {
+				nr, nc, _ := m.neighbor(r, c, side)
+				if !m.isActive(nr, nc) || m.index(nr, nc) < m.index(r, c) {
+					continue
+				}
+				w := Wall{R1: r, C1: c, R2: nr, C2: nc}
+				key := m.weightKey(r, c, side)
+				if _, ok := edgeWeights[key]; ok || bias == DefaultBiasOptions {
+					w.Weight = wallWeight(edgeWeights, key, rng)
 				} else {
-					w.Weight = rand.IntN(100)
+					w.Weight = m.biasedWallWeight(r, c, side, bias, lines, rng)
 				}
 				walls = append(walls, w)
 			}
@@ -99,48 +140,106 @@ func (m *Maze) generateWeightedKruskal(edgeWeights map[string]int) {
 
 	for _, w := range walls {
 		if isImageMode {
-			if w.R1 == w.R2 { 
-				m.Grid[w.R1][w.C1].WallWeights[1] = w.Weight 
-				m.Grid[w.R2][w.C2].WallWeights[3] = w.Weight
-			} else {
-				m.Grid[w.R1][w.C1].WallWeights[2] = w.Weight
-				m.Grid[w.R2][w.C2].WallWeights[0] = w.Weight
-			}
+			m.recordWallWeight(w)
 		}
 
-		id1 := w.R1*m.Cols + w.C1
-		id2 := w.R2*m.Cols + w.C2
+		id1 := m.index(w.R1, w.C1)
+		id2 := m.index(w.R2, w.C2)
 
 		if dsu.Find(id1) != dsu.Find(id2) {
 			m.RemoveWalls(w.R1, w.C1, w.R2, w.C2)
 			dsu.Union(id1, id2)
+			m.trace2(TraceMerge, [2]int{w.R1, w.C1}, [2]int{w.R2, w.C2})
 		}
 	}
 }
 
-// GenerateRecursive sets up the grid with 255 weights before starting the DFS.
-func (m *Maze) GenerateRecursive(r, c int) {
-	// If this is the starting call, initialize the weights. 
-	if r == 0 && c == 0 {
-		m.initializeWallWeights(255)
+// GenerateRecursive sets up the grid with 255 weights before running the
+// backtracking DFS from (r, c).
+func (m *Maze) GenerateRecursive(r, c int) error {
+	return m.generateRecursive(r, c, DefaultBiasOptions, m.Rand())
+}
+
+func (m *Maze) generateRecursive(r, c int, bias BiasOptions, rng *rand.Rand) error {
+	if !m.inBounds(r, c) {
+		return fmt.Errorf("start cell (%d, %d) is outside the %dx%d grid", r, c, m.Rows, m.Cols)
+	}
+	if err := ValidateDimensions(m.Rows, m.Cols); err != nil {
+		return err
+	}
+
+	m.initializeWallWeights(255)
+	if m.Grid[r][c].Masked {
+		r, c = m.randomActiveCell(rng)
 	}
-	m.recursiveDFS(r, c)
+	m.backtrackDFS(r, c, bias, rng)
+	return nil
 }
 
-func (m *Maze) recursiveDFS(r, c int) {
-	m.Grid[r][c].Visited = true
-	dirs := [][]int{{-1, 0}, {0, 1}, {1, 0}, {0, -1}}
-	rand.Shuffle(len(dirs), func(i, j int) {
-		dirs[i], dirs[j] = dirs[j], dirs[i]
-	})
+// dfsFrame is one level of the backtracker's explicit stack: the cell and
+// the shuffled order in which its neighbours are still to be tried.
+type dfsFrame struct {
+	cell  int32
+	dirs  [8]uint8
+	sides uint8
+	next  uint8
+}
 
-	for _, d := range dirs {
-		nextR, nextC := r+d[0], c+d[1]
-		if nextR >= 0 && nextR < m.Rows && nextC >= 0 && nextC < m.Cols {
-			if !m.Grid[nextR][nextC].Visited {
-				m.RemoveWalls(r, c, nextR, nextC)
-				m.recursiveDFS(nextR, nextC)
+// backtrackDFS runs the recursive backtracker over an explicit stack, so a
+// long corridor costs a few bytes per cell instead of a goroutine stack frame.
+// Pieces of a masked shape that (r, c) cannot reach are carved afterwards.
+func (m *Maze) backtrackDFS(r, c int, bias BiasOptions, rng *rand.Rand) {
+	m.backtrackFrom(r, c, bias, rng)
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if m.isActive(r, c) && !m.Grid[r][c].Visited {
+				m.backtrackFrom(r, c, bias, rng)
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+
+func (m *Maze) backtrackFrom(r, c int, bias BiasOptions, rng *rand.Rand) {
+	sides := uint8(m.topo().Sides())
+	// push enters (r, c) through side from, or -1 for the first cell
+	push := func(stack []dfsFrame, r, c, from int) []dfsFrame {
+		m.Grid[r][c].Visited = true
+		m.trace1(TraceVisit, [2]int{r, c})
+		f := dfsFrame{cell: int32(m.index(r, c)), dirs: [8]uint8{0, 1, 2, 3, 4, 5, 6, 7}, sides: sides}
+		if bias.Horizontal != DefaultBiasOptions.Horizontal {
+			m.biasedOrder(r, c, f.dirs[:sides], bias, rng)
+		} else {
+			rng.Shuffle(int(sides), func(i, j int) {
+				f.dirs[i], f.dirs[j] = f.dirs[j], f.dirs[i]
+			})
+		}
+		// a river carries on straight by trying the same side first
+		if from >= 0 && bias.River > 0 && rng.Float64() < bias.River {
+			i := slices.Index(f.dirs[:sides], uint8(from))
+			copy(f.dirs[1:i+1], f.dirs[:i])
+			f.dirs[0] = uint8(from)
+		}
+		return append(stack, f)
+	}
+
+	stack := push(nil, r, c, -1)
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next == top.sides {
+			r, c := m.cellAt(int(top.cell))
+			m.trace1(TraceBacktrack, [2]int{r, c})
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		side := int(top.dirs[top.next])
+		top.next++
+
+		cr, cc := m.cellAt(int(top.cell))
+		nextR, nextC, _ := m.neighbor(cr, cc, side)
+		if m.isActive(nextR, nextC) && !m.Grid[nextR][nextC].Visited {
+			m.RemoveWalls(cr, cc, nextR, nextC)
+			stack = push(stack, nextR, nextC, side)
+		}
+	}
+}
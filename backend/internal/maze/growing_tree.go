@@ -0,0 +1,137 @@
+package maze
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// GrowingTreeStrategy weights how the Growing Tree generator chooses the
+// next active cell to extend. Always picking the newest cell behaves like
+// the recursive backtracker, while always picking at random gives a
+// Prim's-like texture; mixes land in between.
+type GrowingTreeStrategy struct {
+	Newest int `json:"newest"`
+	Oldest int `json:"oldest"`
+	Random int `json:"random"`
+}
+
+// DefaultGrowingTreeStrategy mostly backtracks with occasional random jumps.
+var DefaultGrowingTreeStrategy = GrowingTreeStrategy{Newest: 75, Random: 25}
+
+// ParseGrowingTreeStrategy reads a strategy such as "newest", "random" or a
+// weighted mix like "newest:75,random:25".
+func ParseGrowingTreeStrategy(s string) (GrowingTreeStrategy, error) {
+	var strategy GrowingTreeStrategy
+
+	for _, part := range strings.Split(s, ",") {
+		name, weightStr, hasWeight := strings.Cut(strings.TrimSpace(part), ":")
+		weight := 1
+		if hasWeight {
+			w, err := strconv.Atoi(strings.TrimSpace(weightStr))
+			if err != nil || w < 0 {
+				return strategy, fmt.Errorf("invalid weight %q for %q", weightStr, name)
+			}
+			weight = w
+		}
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "newest":
+			strategy.Newest += weight
+		case "oldest":
+			strategy.Oldest += weight
+		case "random":
+			strategy.Random += weight
+		default:
+			return strategy, fmt.Errorf("unknown selection %q", name)
+		}
+	}
+
+	if strategy.Newest+strategy.Oldest+strategy.Random == 0 {
+		return strategy, fmt.Errorf("strategy %q has no weight", s)
+	}
+	return strategy, nil
+}
+
+// pick returns the index into an active list of length n to extend next.
+func (s GrowingTreeStrategy) pick(n int, rng *rand.Rand) int {
+	roll := rng.IntN(s.Newest + s.Oldest + s.Random)
+	switch {
+	case roll < s.Newest:
+		return n - 1
+	case roll < s.Newest+s.Oldest:
+		return 0
+	default:
+		return rng.IntN(n)
+	}
+}
+
+// GenerateGrowingTree carves a maze by repeatedly extending a cell from an
+// active list, with the cell chosen according to strategy.
+func (m *Maze) GenerateGrowingTree(strategy GrowingTreeStrategy) {
+	m.generateGrowingTree(strategy, m.Rand())
+}
+
+func (m *Maze) generateGrowingTree(strategy GrowingTreeStrategy, rng *rand.Rand) {
+	m.initializeWallWeights(255)
+	if m.Rows == 0 || m.Cols == 0 {
+		return
+	}
+
+	visited := make([]bool, m.cellCount())
+	sr, sc := m.randomActiveCell(rng)
+	m.growTree(Point{sr, sc}, visited, strategy, rng)
+
+	// separate pieces of a masked shape each grow their own tree
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if m.isActive(r, c) && !visited[m.index(r, c)] {
+				m.growTree(Point{r, c}, visited, strategy, rng)
+			}
+		}
+	}
+}
+
+func (m *Maze) growTree(start Point, visited []bool, strategy GrowingTreeStrategy, rng *rand.Rand) {
+	visited[m.index(start[0], start[1])] = true
+	m.trace1(TraceVisit, start)
+	// the live list is active[head:], so the oldest cell leaves by moving
+	// head rather than shifting every cell after it
+	active := []Point{start}
+	head := 0
+
+	var options []Point
+	for head < len(active) {
+		idx := head + strategy.pick(len(active)-head, rng)
+		curr := active[idx]
+
+		options = options[:0]
+		for side := range m.Grid[curr[0]][curr[1]].Walls {
+			nr, nc, _ := m.neighbor(curr[0], curr[1], side)
+			if m.isActive(nr, nc) && !visited[m.index(nr, nc)] {
+				options = append(options, Point{nr, nc})
+			}
+		}
+
+		if len(options) == 0 {
+			m.trace1(TraceBacktrack, curr)
+			// the newest cell fills the gap left by any other, which keeps
+			// removal constant time and moves only one cell out of order
+			if idx == head {
+				head++
+			} else {
+				last := len(active) - 1
+				active[idx] = active[last]
+				active = active[:last]
+			}
+			continue
+		}
+
+		next := options[rng.IntN(len(options))]
+		m.RemoveWalls(curr[0], curr[1], next[0], next[1])
+		visited[m.index(next[0], next[1])] = true
+		m.trace1(TraceVisit, next)
+		active = append(active, next)
+	}
+}
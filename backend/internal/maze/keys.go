@@ -0,0 +1,346 @@
+package maze
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// Keys-and-doors mazes lock some cells behind doors. Door d can only be
+// entered once key d has been picked up, and keys are picked up by walking
+// onto their cell. Keys and doors are numbered from 1, and placeKeys puts
+// key d+1 behind door d so they have to be collected in order.
+
+// MaxKeys is the most key and door pairs a maze may hold.
+const MaxKeys = 8
+
+// HasDoors reports whether any cell is locked behind a door.
+func (m *Maze) HasDoors() bool {
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if m.Grid[r][c].Door > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PlaceKeys locks up to n doors along the route from Start to End and hides
+// the key to each somewhere reachable before it. Start and End must already
+// be placed. It returns the number of pairs placed, which is fewer than n
+// when the route has too few cells to block.
+func (m *Maze) PlaceKeys(n int) int {
+	return m.placeKeys(min(n, MaxKeys), m.Rand())
+}
+
+func (m *Maze) placeKeys(n int, rng *rand.Rand) int {
+	start, end := Point(m.Start), Point(m.End)
+	_, solution := m.SolveBFS()
+	if n <= 0 || len(solution) < 3 {
+		return 0
+	}
+	onPath := make([]bool, m.cellCount())
+	for _, p := range solution {
+		onPath[m.index(p[0], p[1])] = true
+	}
+
+	// spread the doors along the route, each at the nearest cell past the
+	// previous door that cuts the start off from the end
+	chokes := m.chokePoints(solution)
+	var doors []Point
+	prev := 0
+	for i := 1; i <= n; i++ {
+		target := max(i*(len(solution)-1)/(n+1), prev+1)
+		door, at := m.chokeNear(solution, chokes, target, prev+1)
+		if at < 0 {
+			break
+		}
+		doors = append(doors, door)
+		prev = at
+	}
+
+	// key i goes where the player can reach with doors i onward still
+	// locked, but not before passing door i-1. One-way passages could
+	// strand the player there, so the key must also be reachable from
+	// door i-1 and lead back to door i.
+	placed := 0
+	before := make([]bool, m.cellCount())
+	for i, door := range doors {
+		reach := m.reachable(start, doors[i:])
+		from := make([]bool, m.cellCount())
+		if i > 0 {
+			for _, p := range m.reachable(doors[i-1], doors[i:]) {
+				from[m.index(p[0], p[1])] = true
+			}
+		}
+		onward := m.leadsTo(door, doors[i+1:])
+		var offPath, onRoute []Point
+		for _, p := range reach {
+			id := m.index(p[0], p[1])
+			cell := m.Grid[p[0]][p[1]]
+			if before[id] || p == start || p == end || cell.Tunnel || slices.Contains(doors, p) {
+				continue
+			}
+			if (i > 0 && !from[id]) || !onward[id] {
+				continue
+			}
+			if onPath[id] {
+				onRoute = append(onRoute, p)
+			} else {
+				offPath = append(offPath, p)
+			}
+		}
+		candidates := offPath
+		if len(candidates) == 0 {
+			candidates = onRoute
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		key := candidates[rng.IntN(len(candidates))]
+		m.Grid[key[0]][key[1]].Key = i + 1
+		m.Grid[door[0]][door[1]].Door = i + 1
+		placed++
+		for _, p := range reach {
+			before[m.index(p[0], p[1])] = true
+		}
+	}
+	return placed
+}
+
+// chokeNear returns the choke point on route closest to index target, no
+// earlier than index from, along with its index, or -1 if there is none.
+func (m *Maze) chokeNear(route [][2]int, chokes []bool, target, from int) (Point, int) {
+	last := len(route) - 2 // never the end itself
+	for offset := 0; target-offset >= from || target+offset <= last; offset++ {
+		for _, i := range []int{target + offset, target - offset} {
+			if i < from || i > last || !chokes[i] {
+				continue
+			}
+			if p := Point(route[i]); !m.Grid[p[0]][p[1]].Tunnel {
+				return p, i
+			}
+		}
+	}
+	return Point{}, -1
+}
+
+// chokePoints reports, for each cell of route from its first cell to its
+// last, whether every path between the two passes through it. Such cells
+// lie on every route, so one search that follows this one in order finds
+// them all: route[i] is a choke point when nothing reachable from the
+// cells before it, without passing it, reaches further along the route.
+func (m *Maze) chokePoints(route [][2]int) []bool {
+	pos := make([]int, m.cellCount())
+	for i := range pos {
+		pos[i] = -1
+	}
+	for i, p := range route {
+		pos[m.index(p[0], p[1])] = i
+	}
+
+	// explore floods off the route from p, noting the farthest route
+	// cell it touches without stepping onto the route itself
+	seen := make([]bool, m.cellCount())
+	far := 0
+	var stack []Point
+	explore := func(p Point) {
+		stack = append(stack[:0], p)
+		for len(stack) > 0 {
+			cur := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, next := range m.GetNeighbors(cur) {
+				id := m.index(next[0], next[1])
+				if pos[id] >= 0 {
+					far = max(far, pos[id])
+				} else if !seen[id] {
+					seen[id] = true
+					stack = append(stack, next)
+				}
+			}
+		}
+	}
+
+	chokes := make([]bool, len(route))
+	for i, p := range route {
+		chokes[i] = i > 0 && far == i
+		explore(Point(p))
+	}
+	return chokes
+}
+
+// reachable lists the cells reachable from p along the passages without
+// entering any cell in blocked, ignoring doors and keys.
+func (m *Maze) reachable(p Point, blocked []Point) []Point {
+	seen := make([]bool, m.cellCount())
+	seen[m.index(p[0], p[1])] = true
+	cells := []Point{p}
+	for i := 0; i < len(cells); i++ {
+		for _, next := range m.GetNeighbors(cells[i]) {
+			if id := m.index(next[0], next[1]); !seen[id] && !slices.Contains(blocked, next) {
+				seen[id] = true
+				cells = append(cells, next)
+			}
+		}
+	}
+	return cells
+}
+
+// leadsTo marks, by cell index, the cells from which p can be reached
+// along the passages without entering any cell in blocked, ignoring doors
+// and keys.
+func (m *Maze) leadsTo(p Point, blocked []Point) []bool {
+	seen := make([]bool, m.cellCount())
+	seen[m.index(p[0], p[1])] = true
+	for queue := []Point{p}; len(queue) > 0; queue = queue[1:] {
+		cur := queue[0]
+		for side := range m.Grid[cur[0]][cur[1]].Walls {
+			nr, nc, ok := m.passage(cur[0], cur[1], side)
+			prev := Point{nr, nc}
+			if !ok || seen[m.index(nr, nc)] || slices.Contains(blocked, prev) {
+				continue
+			}
+			if back := m.sideTowardsPassage(prev, cur); back >= 0 && m.canExit(nr, nc, back) {
+				seen[m.index(nr, nc)] = true
+				queue = append(queue, prev)
+			}
+		}
+		// portals lead both ways
+		if prev, ok := m.portalExit(cur); ok && !seen[m.index(prev[0], prev[1])] && !slices.Contains(blocked, prev) {
+			seen[m.index(prev[0], prev[1])] = true
+			queue = append(queue, prev)
+		}
+	}
+	return seen
+}
+
+// keyState is a position in a keys-and-doors maze together with the keys
+// held there, one bit per key.
+type keyState struct {
+	p    Point
+	keys uint32
+}
+
+// stateItem is a keyState waiting in a solver's frontier.
+type stateItem struct {
+	state    keyState
+	priority int
+}
+
+type stateQueue []stateItem
+
+func (q stateQueue) Len() int           { return len(q) }
+func (q stateQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q stateQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *stateQueue) Push(x any)        { *q = append(*q, x.(stateItem)) }
+func (q *stateQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// pickUp adds the key lying at p, if any, to keys.
+func (m *Maze) pickUp(p Point, keys uint32) uint32 {
+	if k := m.Grid[p[0]][p[1]].Key; k > 0 {
+		keys |= 1 << (k - 1)
+	}
+	return keys
+}
+
+// solveLocked searches over (cell, keys held) states, so a door is only
+// passed once its key has been picked up and a cell may be revisited with
+// more keys. cost prices entering a cell, and priority orders the frontier
+// from the cost so far and the estimated cost left. The path may visit a
+// cell more than once.
+func (m *Maze) solveLocked(cost func(Point) int, priority func(moves, estimate int) int) ([][2]int, [][2]int) {
+	start, end := Point{m.Start[0], m.Start[1]}, Point{m.End[0], m.End[1]}
+	estimate := m.heuristic()
+
+	first := keyState{start, m.pickUp(start, 0)}
+	visited := [][2]int{}
+	cameFrom := map[keyState]keyState{}
+	moves := map[keyState]int{first: 0}
+
+	pq := &stateQueue{{first, priority(0, estimate(start, end))}}
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(stateItem)
+		curr := item.state
+		if item.priority != priority(moves[curr], estimate(curr.p, end)) {
+			continue // superseded by a shorter route to the same state
+		}
+		visited = append(visited, [2]int(curr.p))
+
+		if curr.p == end {
+			var path [][2]int
+			for s, ok := curr, true; ok; s, ok = cameFrom[s] {
+				path = append(path, [2]int(s.p))
+			}
+			slices.Reverse(path)
+			return visited, path
+		}
+
+		for _, p := range m.GetNeighbors(curr.p) {
+			if d := m.Grid[p[0]][p[1]].Door; d > 0 && curr.keys&(1<<(d-1)) == 0 {
+				continue
+			}
+			next := keyState{p, m.pickUp(p, curr.keys)}
+			g := moves[curr] + cost(p)
+			if old, ok := moves[next]; ok && old <= g {
+				continue
+			}
+			moves[next], cameFrom[next] = g, curr
+			heap.Push(pq, stateItem{next, priority(g, estimate(p, end))})
+		}
+	}
+	return visited, nil
+}
+
+// Feature records the key, door, one-way passages or terrain on one cell,
+// so they can be stored apart from the walls.
+type Feature struct {
+	Row    int   `json:"row"`
+	Col    int   `json:"col"`
+	Key    int   `json:"key,omitempty"`
+	Door   int   `json:"door,omitempty"`
+	OneWay uint8 `json:"one_way,omitempty"`
+	Cost   int   `json:"cost,omitempty"`
+}
+
+// EncodeFeatures lists the maze's keys, doors, one-way passages and
+// terrain as JSON, or returns an empty string if it has none.
+func (m *Maze) EncodeFeatures() string {
+	var features []Feature
+	for r := range m.Grid {
+		for c, cell := range m.Grid[r] {
+			if cell.Key > 0 || cell.Door > 0 || cell.OneWay != 0 || cell.Cost > CostRoad {
+				features = append(features, Feature{Row: r, Col: c, Key: cell.Key, Door: cell.Door, OneWay: cell.OneWay, Cost: cell.Cost})
+			}
+		}
+	}
+	if len(features) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(features)
+	return string(data)
+}
+
+// DecodeFeatures restores the features produced by EncodeFeatures.
+func (m *Maze) DecodeFeatures(data string) error {
+	var features []Feature
+	if err := json.Unmarshal([]byte(data), &features); err != nil {
+		return fmt.Errorf("decode features: %w", err)
+	}
+	for _, f := range features {
+		if !m.isActive(f.Row, f.Col) || f.Key < 0 || f.Key > MaxKeys || f.Door < 0 || f.Door > MaxKeys || f.Cost < 0 || f.Cost > MaxCost {
+			return fmt.Errorf("decode features: invalid feature at (%d, %d)", f.Row, f.Col)
+		}
+		cell := &m.Grid[f.Row][f.Col]
+		cell.Key, cell.Door, cell.OneWay, cell.Cost = f.Key, f.Door, f.OneWay, f.Cost
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package maze
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// braidedMaze builds a Kruskal maze with the given fraction of dead ends
+// braided away and Start and End placed on the border.
+func braidedMaze(t *testing.T, rows, cols int, seed int64, braid float64) *Maze {
+	t.Helper()
+	m := NewMaze(rows, cols)
+	m.SetSeed(seed)
+	gen, err := NewGenerator("kruskal", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.Generate(context.Background(), m, m.Rand()); err != nil {
+		t.Fatal(err)
+	}
+	m.Braid(braid)
+	m.SetRandomStartEnd()
+	return m
+}
+
+func TestChokePoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		braid  float64
+		oneWay float64
+	}{
+		{"perfect", 0, 0},
+		{"half braided", 0.5, 0},
+		{"braided", 1, 0},
+		{"braided with one-way passages", 1, 0.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := range int64(10) {
+				m := braidedMaze(t, 15, 15, seed, tt.braid)
+				m.AddOneWays(tt.oneWay)
+				_, route := m.SolveBFS()
+				chokes := m.chokePoints(route)
+				for i := 1; i < len(route)-1; i++ {
+					p := Point(route[i])
+					want := !slices.Contains(m.reachable(Point(m.Start), []Point{p}), Point(m.End))
+					if chokes[i] != want {
+						t.Fatalf("seed %d: choke point at %v is %v, want %v", seed, p, chokes[i], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPlaceKeysBraided(t *testing.T) {
+	for _, braid := range []float64{0, 0.3, 1} {
+		m := braidedMaze(t, 300, 300, 1, braid)
+		placed := m.PlaceKeys(MaxKeys)
+		if braid == 0 && placed != MaxKeys {
+			t.Errorf("braid %v: placed %d keys on a perfect maze, want %d", braid, placed, MaxKeys)
+		}
+
+		keys, doors := map[int]Point{}, map[int]Point{}
+		for r := range m.Grid {
+			for c, cell := range m.Grid[r] {
+				if cell.Key > 0 {
+					keys[cell.Key] = Point{r, c}
+				}
+				if cell.Door > 0 {
+					doors[cell.Door] = Point{r, c}
+				}
+			}
+		}
+		if len(keys) != placed || len(doors) != placed {
+			t.Fatalf("braid %v: %d keys and %d doors for %d pairs", braid, len(keys), len(doors), placed)
+		}
+		for d, door := range doors {
+			if slices.Contains(m.reachable(Point(m.Start), []Point{door}), Point(m.End)) {
+				t.Errorf("braid %v: door %d at %v can be walked around", braid, d, door)
+			}
+		}
+
+		_, path := m.SolveBFS()
+		if len(path) == 0 {
+			t.Fatalf("braid %v: no solution with %d doors", braid, placed)
+		}
+		for d, door := range doors {
+			if k := slices.Index(path, [2]int(keys[d])); k < 0 || k > slices.Index(path, [2]int(door)) {
+				t.Errorf("braid %v: solution passes door %d without its key", braid, d)
+			}
+		}
+	}
+}
+
+// carvedMaze opens a passage between each pair of cells in links on an
+// otherwise fully walled rows x cols grid.
+func carvedMaze(t *testing.T, rows, cols int, start, end Point, links ...[2]Point) *Maze {
+	t.Helper()
+	m := NewMaze(rows, cols)
+	for _, l := range links {
+		m.RemoveWalls(l[0][0], l[0][1], l[1][0], l[1][1])
+	}
+	if err := m.SetManualStartEnd(start[0], start[1], end[0], end[1]); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// checkSolvers runs every optimal solver on m and checks that each finds a
+// legal path from Start to End of want cells, or none when want is 0.
+func checkSolvers(t *testing.T, m *Maze, want int) {
+	t.Helper()
+	for _, name := range []string{"astar", "dijkstra", "bfs"} {
+		solver, _ := LookupSolver(name)
+		_, path := solver.Solve(m)
+		if len(path) != want {
+			t.Errorf("%s: path has %d cells, want %d: %v", name, len(path), want, path)
+			continue
+		}
+		if want == 0 {
+			continue
+		}
+		if path[0] != m.Start || path[len(path)-1] != m.End {
+			t.Errorf("%s: path runs from %v to %v, want %v to %v", name, path[0], path[len(path)-1], m.Start, m.End)
+		}
+		for i := 1; i < len(path); i++ {
+			if !slices.Contains(m.GetNeighbors(Point(path[i-1])), Point(path[i])) {
+				t.Errorf("%s: step %d from %v to %v is not a move", name, i, path[i-1], path[i])
+			}
+		}
+	}
+}
+
+// keyedMaze is a corridor locked by door 1 and door 2, with a side room
+// before each door holding the key placed there.
+//
+//	S D . D E
+//	k   k
+func keyedMaze(t *testing.T, first, second int) *Maze {
+	t.Helper()
+	m := carvedMaze(t, 2, 5, Point{0, 0}, Point{0, 4},
+		[2]Point{{0, 0}, {0, 1}}, [2]Point{{0, 1}, {0, 2}}, [2]Point{{0, 2}, {0, 3}}, [2]Point{{0, 3}, {0, 4}},
+		[2]Point{{0, 0}, {1, 0}}, [2]Point{{0, 2}, {1, 2}})
+	m.Grid[0][1].Door, m.Grid[0][3].Door = 1, 2
+	m.Grid[1][0].Key, m.Grid[1][2].Key = first, second
+	return m
+}
+
+func TestSolveKeys(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second int
+		want          int
+	}{
+		{"keys collected in order", 1, 2, 9},
+		{"key locked behind its own door", 2, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkSolvers(t, keyedMaze(t, tt.first, tt.second), tt.want)
+		})
+	}
+}
+
+func TestKeysRoundTrip(t *testing.T) {
+	m := braidedMaze(t, 20, 20, 7, 0)
+	if m.PlaceKeys(3) == 0 {
+		t.Fatal("no keys placed")
+	}
+
+	restored := NewMaze(m.Rows, m.Cols)
+	if err := restored.DecodeFeatures(m.EncodeFeatures()); err != nil {
+		t.Fatal(err)
+	}
+	for r := range m.Grid {
+		for c, cell := range m.Grid[r] {
+			if got := restored.Grid[r][c]; got.Key != cell.Key || got.Door != cell.Door {
+				t.Errorf("cell (%d, %d) restored with key %d and door %d, want %d and %d", r, c, got.Key, got.Door, cell.Key, cell.Door)
+			}
+		}
+	}
+}
+
+func TestDecodeFeaturesRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		features string
+	}{
+		{"key out of range", `[{"row":0,"col":0,"key":9}]`},
+		{"door out of range", `[{"row":0,"col":0,"door":-1}]`},
+		{"feature off the grid", `[{"row":5,"col":0,"door":1}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewMaze(4, 5).DecodeFeatures(tt.features); err == nil {
+				t.Error("decoded without error")
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package maze
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// MaxLevels is the most floors a multi-level maze may have.
+const MaxLevels = 16
+
+// Multi-level mazes stack their floors in Grid: level l holds grid rows
+// l*Rows to (l+1)*Rows - 1, so a Point's row runs across floors and every
+// generator, solver and encoder works on them unchanged. Each floor is laid
+// out by the maze's base topology, and every cell gains two more sides,
+// up and down, leading to the same position on the neighbouring floors.
+
+// layeredTopology adds stairs between the floors of a base topology.
+type layeredTopology struct {
+	base   Topology
+	rows   int // rows per floor
+	levels int
+}
+
+func (t layeredTopology) Name() string { return t.base.Name() }
+func (t layeredTopology) Sides() int   { return t.base.Sides() + 2 }
+
+func (t layeredTopology) SideNames() []string {
+	return append(t.base.SideNames(), "up", "down")
+}
+
+// RowLengths repeats the base layout once per floor.
+func (t layeredTopology) RowLengths(rows, cols int) []int {
+	floor := t.base.RowLengths(rows, cols)
+	lengths := make([]int, 0, len(floor)*t.levels)
+	for range t.levels {
+		lengths = append(lengths, floor...)
+	}
+	return lengths
+}
+
+func (t layeredTopology) Neighbor(m *Maze, r, c, side int) (int, int) {
+	level, row := r/m.Rows, r%m.Rows
+	switch side - t.base.Sides() {
+	case 0: // UP
+		if level == t.levels-1 {
+			return outside, outside
+		}
+		return r + m.Rows, c
+	case 1: // DOWN
+		if level == 0 {
+			return outside, outside
+		}
+		return r - m.Rows, c
+	}
+
+	// every floor has the same row lengths, so the base topology can lay
+	// out neighbours as if the cell were on the ground floor
+	nr, nc := t.base.Neighbor(m, row, c, side)
+	if nr < 0 || nr >= m.Rows {
+		return outside, outside
+	}
+	return nr + level*m.Rows, nc
+}
+
+// HasSide drops the stairs leading above the top floor and below the
+// ground floor, so they never count as part of the border.
+func (t layeredTopology) HasSide(m *Maze, r, c, side int) bool {
+	level := r / m.Rows
+	switch side - t.base.Sides() {
+	case 0:
+		return level < t.levels-1
+	case 1:
+		return level > 0
+	}
+	if p, ok := t.base.(partialSides); ok {
+		return p.HasSide(m, r%m.Rows, c, side)
+	}
+	return true
+}
+
+// Distance adds the floors to climb to the base distance between the two
+// positions, as each stair moves one floor and nothing else.
+func (t layeredTopology) Distance(a, b Point) int {
+	climb := abs(a[0]/t.rows - b[0]/t.rows)
+	return climb + t.base.Distance(Point{a[0] % t.rows, a[1]}, Point{b[0] % t.rows, b[1]})
+}
+
+// PlaceStartEnd enters the maze on the ground floor and leaves it from
+// the top floor, so every solution has to climb.
+func (t layeredTopology) PlaceStartEnd(m *Maze, rng *rand.Rand) (Point, Point) {
+	top := (t.levels - 1) * m.Rows
+	if p, ok := t.base.(startEndPlacer); ok {
+		start, end := p.PlaceStartEnd(m, rng)
+		return start, Point{end[0] + top, end[1]}
+	}
+
+	outside := m.exterior()
+	pick := func(first int) Point {
+		var border []Point
+		for r := first; r < first+m.Rows; r++ {
+			for c := range m.Grid[r] {
+				if m.isBorder(r, c, outside) {
+					border = append(border, Point{r, c})
+				}
+			}
+		}
+		return border[rng.IntN(len(border))]
+	}
+	return pick(0), pick(top)
+}
+
+// floorTopo returns the topology each floor is laid out with.
+func (m *Maze) floorTopo() Topology {
+	if t, ok := m.topo().(layeredTopology); ok {
+		return t.base
+	}
+	return m.topo()
+}
+
+// shape returns the topology that gives cells their shape, beneath any
+// wrapping or stacking of floors.
+func (m *Maze) shape() Topology {
+	t := m.floorTopo()
+	if w, ok := t.(wrapTopology); ok {
+		return w.base
+	}
+	return t
+}
+
+// layout describes the grid for error messages, e.g. "square" or
+// "3-level torus hex".
+func (m *Maze) layout() string {
+	layout := m.Topology
+	if m.Wrap != WrapNone {
+		layout = m.Wrap + " " + layout
+	}
+	if m.Levels > 1 {
+		layout = fmt.Sprintf("%d-level %s", m.Levels, layout)
+	}
+	return layout
+}
+
+// floor returns level l as a single-level maze for rendering one floor at
+// a time. Cells keep their walls but drop the stairs, and the entrance,
+// exit and portal ends only appear on the floor they belong to.
+func (m *Maze) floor(l int) *Maze {
+	sides := m.floorTopo().Sides()
+	grid := make([][]Cell, m.Rows)
+	for r := range grid {
+		row := m.Grid[l*m.Rows+r]
+		grid[r] = make([]Cell, len(row))
+		for c, cell := range row {
+			cell.Walls, cell.WallWeights = cell.Walls[:sides], cell.WallWeights[:sides]
+			grid[r][c] = cell
+		}
+	}
+
+	local := func(p [2]int) [2]int {
+		if p[0]/m.Rows != l {
+			return [2]int{-1, -1}
+		}
+		return [2]int{p[0] % m.Rows, p[1]}
+	}
+	// pairs keep their index, and so their colour, on every floor, with the
+	// ends on other floors left off the grid
+	var portals []Portal
+	for _, portal := range m.Portals {
+		portals = append(portals, Portal{local(portal[0]), local(portal[1])})
+	}
+	return &Maze{
+		Rows:     m.Rows,
+		Cols:     m.Cols,
+		Grid:     grid,
+		Start:    local(m.Start),
+		End:      local(m.End),
+		Portals:  portals,
+		Topology: m.Topology,
+		Wrap:     m.Wrap,
+		Levels:   1,
+		topology: m.floorTopo(),
+	}
+}
@@ -0,0 +1,273 @@
+package maze
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// SetMask removes cells from the maze so it can take an arbitrary shape.
+// disabled must match the grid dimensions, with one row per grid row on
+// every level; true marks a cell that does
+// not exist. Masked cells stay fully walled and are skipped by generators,
+// solvers, statistics and rendering.
+func (m *Maze) SetMask(disabled [][]bool) error {
+	if len(disabled) != len(m.Grid) {
+		return fmt.Errorf("mask has %d rows, maze has %d", len(disabled), len(m.Grid))
+	}
+
+	active := 0
+	for r := range disabled {
+		if len(disabled[r]) != len(m.Grid[r]) {
+			return fmt.Errorf("mask row %d has %d columns, maze has %d", r, len(disabled[r]), len(m.Grid[r]))
+		}
+		for _, off := range disabled[r] {
+			if !off {
+				active++
+			}
+		}
+	}
+	if active == 0 {
+		return fmt.Errorf("mask disables every cell")
+	}
+
+	for r := range disabled {
+		for c, off := range disabled[r] {
+			m.Grid[r][c].Masked = off
+			if off {
+				for side := range m.Grid[r][c].Walls {
+					m.Grid[r][c].Walls[side] = true
+					if nr, nc, ok := m.neighbor(r, c, side); ok {
+						m.AddWalls(r, c, nr, nc)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// MaskFromCells builds a mask matching the maze's grid with the listed
+// cells disabled.
+func (m *Maze) MaskFromCells(cells [][2]int) ([][]bool, error) {
+	mask := m.emptyMask()
+	for _, p := range cells {
+		if !m.inBounds(p[0], p[1]) {
+			return nil, fmt.Errorf("masked cell (%d, %d) is outside the grid", p[0], p[1])
+		}
+		mask[p[0]][p[1]] = true
+	}
+	return mask, nil
+}
+
+// emptyMask returns a mask shaped like the grid with no cell disabled.
+func (m *Maze) emptyMask() [][]bool {
+	mask := make([][]bool, len(m.Grid))
+	for r := range mask {
+		mask[r] = make([]bool, len(m.Grid[r]))
+	}
+	return mask
+}
+
+// HasMask reports whether any cell has been masked out.
+func (m *Maze) HasMask() bool {
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if m.Grid[r][c].Masked {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *Maze) inBounds(r, c int) bool {
+	return r >= 0 && r < len(m.Grid) && c >= 0 && c < len(m.Grid[r])
+}
+
+// isActive reports whether (r, c) is inside the grid and not masked.
+func (m *Maze) isActive(r, c int) bool {
+	return m.inBounds(r, c) && !m.Grid[r][c].Masked
+}
+
+// isBorder reports whether an active cell touches the edge of the grid or
+// a masked cell outside the maze, i.e. the outline of the maze's shape.
+// outside comes from exterior.
+func (m *Maze) isBorder(r, c int, outside []bool) bool {
+	if !m.isActive(r, c) {
+		return false
+	}
+	for side := range m.Grid[r][c].Walls {
+		if m.facesOut(r, c, side, outside) {
+			return true
+		}
+	}
+	return false
+}
+
+// facesOut reports whether side of (r, c) leads out of the maze, past the
+// edge of the grid or into a masked cell marked in outside.
+func (m *Maze) facesOut(r, c, side int, outside []bool) bool {
+	if !m.hasSide(r, c, side) {
+		return false
+	}
+	nr, nc, _ := m.neighbor(r, c, side)
+	if !m.inBounds(nr, nc) {
+		return true
+	}
+	return m.Grid[nr][nc].Masked && outside[m.index(nr, nc)]
+}
+
+// exterior marks, by cell index, the masked cells joined to the space
+// beyond the grid through other masked cells. Pockets the maze encloses,
+// such as a dungeon's rock, are left unmarked so no entrance opens into
+// them. A torus has no edge, so all of its mask counts as outside.
+func (m *Maze) exterior() []bool {
+	outside := make([]bool, m.cellCount())
+	var queue []Point
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if !m.Grid[r][c].Masked {
+				continue
+			}
+			for side := range m.Grid[r][c].Walls {
+				nr, nc, _ := m.neighbor(r, c, side)
+				if m.Wrap == WrapTorus || (!m.inBounds(nr, nc) && m.hasSide(r, c, side)) {
+					outside[m.index(r, c)] = true
+					queue = append(queue, Point{r, c})
+					break
+				}
+			}
+		}
+	}
+	for ; len(queue) > 0; queue = queue[1:] {
+		p := queue[0]
+		for side := range m.Grid[p[0]][p[1]].Walls {
+			nr, nc, _ := m.neighbor(p[0], p[1], side)
+			if m.inBounds(nr, nc) && m.Grid[nr][nc].Masked && !outside[m.index(nr, nc)] {
+				outside[m.index(nr, nc)] = true
+				queue = append(queue, Point{nr, nc})
+			}
+		}
+	}
+	return outside
+}
+
+// randomActiveCell picks a random unmasked cell by rejection sampling,
+// which also skips past the end of rows shorter than Cols.
+func (m *Maze) randomActiveCell(rng *rand.Rand) (int, int) {
+	for {
+		r, c := rng.IntN(len(m.Grid)), rng.IntN(m.Cols)
+		if m.isActive(r, c) {
+			return r, c
+		}
+	}
+}
+
+// regions labels every active cell with the index of the connected piece
+// of the shape it belongs to, ignoring walls. Masked cells get -1.
+func (m *Maze) regions() ([]int, int) {
+	labels := make([]int, m.cellCount())
+	for i := range labels {
+		labels[i] = -1
+	}
+
+	count := 0
+	var queue []int
+	for start := range labels {
+		if sr, sc := m.cellAt(start); labels[start] >= 0 || m.Grid[sr][sc].Masked {
+			continue
+		}
+		labels[start] = count
+		queue = append(queue[:0], start)
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for side := range m.topo().Sides() {
+				cr, cc := m.cellAt(cur)
+				nr, nc, _ := m.neighbor(cr, cc, side)
+				if m.isActive(nr, nc) && labels[m.index(nr, nc)] < 0 {
+					labels[m.index(nr, nc)] = count
+					queue = append(queue, m.index(nr, nc))
+				}
+			}
+		}
+		count++
+	}
+	return labels, count
+}
+
+// connectRegions knocks down walls in random order until every piece of
+// the shape is a single connected maze. Wall-adding generators use it to
+// repair chambers that a mask cut off.
+func (m *Maze) connectRegions(rng *rand.Rand) {
+	dsu := NewDSU(m.cellCount())
+	var walls []Wall
+
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if !m.isActive(r, c) {
+				continue
+			}
+			id := m.index(r, c)
+			for side, isWall := range m.Grid[r][c].Walls {
+				nr, nc, _ := m.neighbor(r, c, side)
+				if !m.isActive(nr, nc) || m.index(nr, nc) < id {
+					continue
+				}
+				if isWall {
+					walls = append(walls, Wall{R1: r, C1: c, R2: nr, C2: nc})
+				} else {
+					dsu.Union(id, m.index(nr, nc))
+				}
+			}
+		}
+	}
+
+	rng.Shuffle(len(walls), func(i, j int) {
+		walls[i], walls[j] = walls[j], walls[i]
+	})
+	for _, w := range walls {
+		id1, id2 := m.index(w.R1, w.C1), m.index(w.R2, w.C2)
+		if dsu.Find(id1) != dsu.Find(id2) {
+			m.RemoveWalls(w.R1, w.C1, w.R2, w.C2)
+			dsu.Union(id1, id2)
+		}
+	}
+}
+
+// setOutlineStartEnd places the entrance and exit on the outline of a
+// masked or non-rectangular shape. Both must lie in the same piece of the
+// shape; if no pair reaches minDist within a bounded number of draws, the
+// farthest pair found is used.
+func (m *Maze) setOutlineStartEnd(minDist float64, rng *rand.Rand) {
+	var border []Point
+	outside := m.exterior()
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if m.isBorder(r, c, outside) {
+				border = append(border, Point{r, c})
+			}
+		}
+	}
+	labels, _ := m.regions()
+
+	start, end := border[0], border[0]
+	bestDist := -1
+	for attempt := 0; attempt < 1000; attempt++ {
+		s := border[rng.IntN(len(border))]
+		e := border[rng.IntN(len(border))]
+		if s == e || labels[m.index(s[0], s[1])] != labels[m.index(e[0], e[1])] {
+			continue
+		}
+		if dist := m.distance(s, e); dist > bestDist {
+			start, end, bestDist = s, e, dist
+			if float64(dist) >= minDist {
+				break
+			}
+		}
+	}
+
+	m.Start, m.End = start, end
+	m.clipBorderWall(start[0], start[1])
+	m.clipBorderWall(end[0], end[1])
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand/v2"
+	"slices"
 )
 
 // Maze represents the overall grid structure.
@@ -19,6 +20,42 @@ type Maze struct {
     Weights    map[string]int `json:"weights"`
     DeadEnds   int            `json:"dead_ends"`
     Complexity float64        `json:"complexity"`
+    Seed       int64          `json:"seed"`
+    Topology   string         `json:"topology"`
+    Levels     int            `json:"levels"` // floors stacked in Grid, see levels.go
+    Wrap       string         `json:"wrap,omitempty"` // joined edges, see wrap.go
+    Placement  string         `json:"placement,omitempty"` // how Start and End were chosen, see placement.go
+    Portals    []Portal       `json:"portals,omitempty"`   // see portal.go
+
+    Trace          []TraceEvent `json:"trace,omitempty"`
+    TraceTruncated bool         `json:"trace_truncated,omitempty"`
+
+    rng         *rand.Rand
+    tracing     bool
+    topology    Topology
+    offsets     []int // see rowOffsets
+    rectangular bool
+}
+
+// NewSeed returns a random seed that stays exact when round-tripped
+// through a JavaScript number on the frontend.
+func NewSeed() int64 {
+	return rand.Int64N(1 << 53)
+}
+
+// SetSeed fixes the seed used by every random decision made on this maze,
+// so the same seed, dimensions and algorithm always produce the same grid.
+func (m *Maze) SetSeed(seed int64) {
+	m.Seed = seed
+	m.rng = rand.New(rand.NewPCG(uint64(seed), 0))
+}
+
+// Rand returns the per-maze RNG, creating it from Seed on first use.
+func (m *Maze) Rand() *rand.Rand {
+	if m.rng == nil {
+		m.SetSeed(m.Seed)
+	}
+	return m.rng
 }
 
 // Cell represents a single coordinate in the maze.
@@ -28,8 +65,14 @@ type Cell struct {
 	Row         int     `json:"row"`
 	Col         int     `json:"col"`
 	Visited     bool    `json:"visited"`
-	Walls       [4]bool `json:"walls"`        // 0:Top, 1:Right, 2:Bottom, 3:Left
-	WallWeights [4]int  `json:"wall_weights"`
+	Walls       []bool `json:"walls"` // one per side of the topology, e.g. 0:Top, 1:Right, 2:Bottom, 3:Left
+	WallWeights []int  `json:"wall_weights"`
+	Masked      bool   `json:"masked,omitempty"` // outside the maze's shape
+	Tunnel      bool   `json:"tunnel,omitempty"` // another passage crosses underneath, see weave.go
+	Key         int    `json:"key,omitempty"`    // key lying here, see keys.go
+	Door        int    `json:"door,omitempty"`   // key needed to enter
+	OneWay      uint8  `json:"one_way,omitempty"` // sides that cannot be left through, one bit each, see oneway.go
+	Cost        int    `json:"cost,omitempty"`    // terrain cost to enter, see terrain.go
 }
 
 // Fun statistics on mazes
@@ -38,16 +81,15 @@ type MazeStats struct {
     Junctions     int     `json:"junctions"`
     StraightWays  int     `json:"straight_ways"`
     Complexity    float64 `json:"complexity"`
+    Loops         int     `json:"loops"`
 }
 
-// SetManualStartEnd allows specific placement of entrance/exit
+// SetManualStartEnd allows specific placement of entrance/exit. Either may
+// be inside the maze, as PlaceInterior puts them, or on a torus, which has
+// no border; only border cells have a wall opened to the outside.
 func (m *Maze) SetManualStartEnd(sr, sc, er, ec int) error {
-	isBorder := func(r, c int) bool {
-		return r == 0 || r == m.Rows-1 || c == 0 || c == m.Cols-1
-	}
-
-	if !isBorder(sr, sc) || !isBorder(er, ec) {
-		return fmt.Errorf("start and end points must be on the maze border")
+	if !m.isActive(sr, sc) || !m.isActive(er, ec) {
+		return fmt.Errorf("start and end points must be cells of the maze")
 	}
 
 	m.Start = [2]int{sr, sc}
@@ -62,16 +104,35 @@ func (m *Maze) SetManualStartEnd(sr, sc, er, ec int) error {
 func (m *Maze) SetRandomStartEnd() {
 	// Manhattan distance threshold (at least 50% of max)
 	minDist := float64(m.Rows+m.Cols) * 0.5
+	rng := m.Rand()
+
+	if p, ok := m.topo().(startEndPlacer); ok && !m.HasMask() {
+		start, end := p.PlaceStartEnd(m, rng)
+		m.Start, m.End = start, end
+		m.clipBorderWall(start[0], start[1])
+		m.clipBorderWall(end[0], end[1])
+		return
+	}
+	if m.HasMask() || !m.isRectangular() {
+		m.setOutlineStartEnd(minDist, rng)
+		return
+	}
 
-	for {
-		sR, sC := m.getRandomBorderPoint()
-		eR, eC := m.getRandomBorderPoint()
+	// tiny grids may have no pair far enough apart, so settle for the
+	// farthest seen rather than spinning
+	bestDist := -1.0
+	for attempt := 0; attempt < 1000; attempt++ {
+		sR, sC := m.getRandomBorderPoint(rng)
+		eR, eC := m.getRandomBorderPoint(rng)
 
 		dist := math.Abs(float64(sR-eR)) + math.Abs(float64(sC-eC))
-		if (sR != eR || sC != eC) && dist >= minDist {
+		if (sR != eR || sC != eC) && dist > bestDist {
 			m.Start = [2]int{sR, sC}
 			m.End = [2]int{eR, eC}
-			break
+			bestDist = dist
+			if dist >= minDist {
+				break
+			}
 		}
 	}
 
@@ -79,52 +140,162 @@ func (m *Maze) SetRandomStartEnd() {
 	m.clipBorderWall(m.End[0], m.End[1])
 }
 
-func (m *Maze) getRandomBorderPoint() (int, int) {
-	side := rand.IntN(4)
+func (m *Maze) getRandomBorderPoint(rng *rand.Rand) (int, int) {
+	side := rng.IntN(4)
 	switch side {
 	case 0:
-		return 0, rand.IntN(m.Cols)
+		return 0, rng.IntN(m.Cols)
 	case 1:
-		return rand.IntN(m.Rows), m.Cols - 1
+		return rng.IntN(m.Rows), m.Cols - 1
 	case 2:
-		return m.Rows - 1, rand.IntN(m.Cols)
+		return m.Rows - 1, rng.IntN(m.Cols)
 	default:
-		return rand.IntN(m.Rows), 0
+		return rng.IntN(m.Rows), 0
 	}
 }
 
+// clipBorderWall opens every wall of (r, c) that faces out of the maze,
+// whether past the grid edge or into a masked cell outside it.
 func (m *Maze) clipBorderWall(r, c int) {
-	if r == 0 {
-		m.Grid[r][c].Walls[0] = false
-	}
-	if r == m.Rows-1 {
-		m.Grid[r][c].Walls[2] = false
+	outside := m.exterior()
+	for i := range m.Grid[r][c].Walls {
+		if m.facesOut(r, c, i, outside) {
+			m.Grid[r][c].Walls[i] = false
+		}
 	}
-	if c == 0 {
-		m.Grid[r][c].Walls[3] = false
+}
+
+// MaxCells is the largest grid the generators accept. Every generator,
+// including the backtracker, keeps its working state proportional to this.
+const MaxCells = 4_000_000
+
+// ValidateDimensions rejects grids that are empty or larger than MaxCells.
+func ValidateDimensions(rows, cols int) error {
+	if rows < 1 || cols < 1 {
+		return fmt.Errorf("maze must have at least one row and column, got %dx%d", rows, cols)
 	}
-	if c == m.Cols-1 {
-		m.Grid[r][c].Walls[1] = false
+	if rows > MaxCells/cols {
+		return fmt.Errorf("maze of %dx%d exceeds the %d cell limit", rows, cols, MaxCells)
 	}
+	return nil
 }
 
-// NewMaze initializes a grid where every cell is completely enclosed.
+// NewMaze initializes a square grid where every cell is completely enclosed.
 func NewMaze(rows, cols int) *Maze {
-	grid := make([][]Cell, rows)
+	return newMaze(rows, cols, squareTopology{})
+}
 
-	for r := range rows {
-		grid[r] = make([]Cell, cols)
+// NewMazeWithTopology initializes a fully enclosed grid using the named
+// topology, such as "square", "hex" or "polar". Polar mazes have rows
+// rings and ignore cols.
+func NewMazeWithTopology(rows, cols int, topology string) (*Maze, error) {
+	return NewMazeWithLevels(rows, cols, 1, topology)
+}
 
-		for c := range cols {
+// NewMazeWithLevels initializes a fully enclosed maze of levels floors,
+// each a rows x cols grid of the named topology, joined by stairs.
+func NewMazeWithLevels(rows, cols, levels int, topology string) (*Maze, error) {
+	t, err := LookupTopology(topology)
+	if err != nil {
+		return nil, err
+	}
+	if levels < 1 || levels > MaxLevels {
+		return nil, fmt.Errorf("maze must have between 1 and %d levels, got %d", MaxLevels, levels)
+	}
+	if levels > 1 {
+		t = layeredTopology{base: t, rows: rows, levels: levels}
+	}
+	total := 0
+	for _, n := range t.RowLengths(rows, cols) {
+		total += n
+	}
+	if total > MaxCells {
+		return nil, fmt.Errorf("%s maze of %dx%dx%d exceeds the %d cell limit", t.Name(), rows, cols, levels, MaxCells)
+	}
+	return newMaze(rows, cols, t), nil
+}
+
+func newMaze(rows, cols int, t Topology) *Maze {
+	sides := t.Sides()
+	lengths := t.RowLengths(rows, cols)
+	grid := make([][]Cell, len(lengths))
+
+	for r := range grid {
+		grid[r] = make([]Cell, lengths[r])
+		// one allocation per row instead of two per cell
+		walls := make([]bool, lengths[r]*sides)
+		weights := make([]int, lengths[r]*sides)
+		for i := range walls {
+			walls[i] = true
+		}
+
+		for c := range lengths[r] {
+			lo, hi := c*sides, (c+1)*sides
 			grid[r][c] = Cell{
-				Row:   r,
-				Col:   c,
-				Walls: [4]bool{true, true, true, true},
+				Row:         r,
+				Col:         c,
+				Walls:       walls[lo:hi:hi],
+				WallWeights: weights[lo:hi:hi],
 			}
 		}
 	}
 
-	return &Maze{Rows: rows, Cols: cols, Grid: grid}
+	// Cols is the widest row, which is every row on rectangular grids
+	if rows > 0 {
+		cols = slices.Max(lengths)
+	}
+	levels := 1
+	if l, ok := t.(layeredTopology); ok {
+		levels = l.levels
+	}
+	return &Maze{Rows: rows, Cols: cols, Grid: grid, Topology: t.Name(), Levels: levels, topology: t}
+}
+
+// Validate checks that a maze decoded from a client is consistent with its
+// own dimensions, topology and wrap, so the solvers and renderer can index
+// every cell, side and feature it names without going out of range.
+func (m *Maze) Validate() error {
+	t, err := LookupTopology(m.Topology)
+	if err != nil {
+		return err
+	}
+	levels := max(m.Levels, 1)
+	if levels > MaxLevels {
+		return fmt.Errorf("maze must have between 1 and %d levels, got %d", MaxLevels, m.Levels)
+	}
+	if m.Rows < 1 || m.Cols < 1 || m.Rows > MaxCells/levels {
+		return fmt.Errorf("maze of %dx%dx%d has invalid dimensions", m.Rows, m.Cols, levels)
+	}
+	m.topology = nil
+	if err := m.SetWrap(m.Wrap); err != nil {
+		return err
+	}
+
+	lengths := m.topo().RowLengths(m.Rows, m.Cols)
+	if len(m.Grid) != len(lengths) {
+		return fmt.Errorf("grid has %d rows, a %s maze of %d rows has %d", len(m.Grid), t.Name(), m.Rows, len(lengths))
+	}
+	total, sides := 0, m.topo().Sides()
+	for r, n := range lengths {
+		if total += n; total > MaxCells {
+			return fmt.Errorf("maze exceeds the %d cell limit", MaxCells)
+		}
+		if len(m.Grid[r]) != n {
+			return fmt.Errorf("grid row %d has %d cells, expected %d", r, len(m.Grid[r]), n)
+		}
+		for c, cell := range m.Grid[r] {
+			if len(cell.Walls) != sides || len(cell.WallWeights) != sides {
+				return fmt.Errorf("cell (%d, %d) must have %d walls and wall weights", r, c, sides)
+			}
+			if cell.Key < 0 || cell.Key > MaxKeys || cell.Door < 0 || cell.Door > MaxKeys || cell.Cost < 0 || cell.Cost > MaxCost {
+				return fmt.Errorf("cell (%d, %d) has an invalid feature", r, c)
+			}
+		}
+	}
+	if !m.inBounds(m.Start[0], m.Start[1]) || !m.inBounds(m.End[0], m.End[1]) {
+		return fmt.Errorf("start and end points must be cells of the maze")
+	}
+	return m.validatePortals(m.Portals)
 }
 
 // Print outputs a rough ASCII representation of the maze to the terminal.
@@ -171,49 +342,44 @@ func (m *Maze) Print() {
 
 // RemoveWalls breaks the boundaries between two adjacent cells.
 func (m *Maze) RemoveWalls(r1, c1, r2, c2 int) {
-	if r1 == r2 {
-		// Horizontal neighbors
-		if c1 < c2 {
-			m.Grid[r1][c1].Walls[1] = false // Right
-			m.Grid[r2][c2].Walls[3] = false // Left
-		} else {
-			m.Grid[r1][c1].Walls[3] = false // Left
-			m.Grid[r2][c2].Walls[1] = false // Right
-		}
+	m.setWalls(r1, c1, r2, c2, false)
+}
+
+// AddWalls restores the boundaries between two adjacent cells.
+func (m *Maze) AddWalls(r1, c1, r2, c2 int) {
+	m.setWalls(r1, c1, r2, c2, true)
+}
+
+func (m *Maze) setWalls(r1, c1, r2, c2 int, isWall bool) {
+	if isWall {
+		m.trace2(TraceAdd, [2]int{r1, c1}, [2]int{r2, c2})
 	} else {
-		// Vertical neighbors
-		if r1 < r2 {
-			m.Grid[r1][c1].Walls[2] = false // Bottom
-			m.Grid[r2][c2].Walls[0] = false // Top
-		} else {
-			m.Grid[r1][c1].Walls[0] = false // Top
-			m.Grid[r2][c2].Walls[2] = false // Bottom
-		}
+		m.trace2(TraceRemove, [2]int{r1, c1}, [2]int{r2, c2})
 	}
+
+	m.Grid[r1][c1].Walls[m.sideTowards(r1, c1, r2, c2)] = isWall
+	m.Grid[r2][c2].Walls[m.sideTowards(r2, c2, r1, c1)] = isWall
 }
 
 // GetNeighbors returns a slice of adjacent points that can be reached from 
 // the current point (i.e., they are within bounds and not blocked by a wall).
+// A tunnel leads to the cell beyond the crossing it passes under, a
+// one-way passage only leads out of the cell it points away from, and a
+// portal leads to its other end.
 func (m *Maze) GetNeighbors(p Point) []Point {
 	neighbors := []Point{}
 	r, c := p[0], p[1]
 
-	dirs := [][]int{
-		{-1, 0, 0}, // North
-		{0, 1, 1},  // East
-		{1, 0, 2},  // South
-		{0, -1, 3}, // West
-	}
-
-	for _, d := range dirs {
-		nr, nc := r+d[0], c+d[1]
-		wallIdx := d[2]
-
-		if nr >= 0 && nr < m.Rows && nc >= 0 && nc < m.Cols {
-			if !m.Grid[r][c].Walls[wallIdx] {
-				neighbors = append(neighbors, Point{nr, nc})
-			}
+	for side := range m.Grid[r][c].Walls {
+		if !m.canExit(r, c, side) {
+			continue
 		}
+		if nr, nc, ok := m.passage(r, c, side); ok {
+			neighbors = append(neighbors, Point{nr, nc})
+		}
+	}
+	if exit, ok := m.portalExit(p); ok {
+		neighbors = append(neighbors, exit)
 	}
 
 	return neighbors
@@ -223,10 +389,15 @@ func (m *Maze) GetNeighbors(p Point) []Point {
 // deadends, straightways, complexity, and junctions
 func (m *Maze) CalculateStats() MazeStats {
     stats := MazeStats{}
-    totalCells := float64(m.Rows * m.Cols)
+    totalCells := 0.0
+
+    for r := range m.Grid {
+        for c := range m.Grid[r] {
+            if m.Grid[r][c].Masked {
+                continue
+            }
+            totalCells++
 
-    for r := 0; r < m.Rows; r++ {
-        for c := 0; c < m.Cols; c++ {
             openCount := 0
             for _, isWall := range m.Grid[r][c].Walls {
                 if !isWall {
@@ -234,12 +405,12 @@ func (m *Maze) CalculateStats() MazeStats {
                 }
             }
 
-            switch openCount {
-            case 1:
+            switch {
+            case openCount == 1:
                 stats.DeadEnds++
-            case 2:
+            case openCount == 2:
                 stats.StraightWays++
-            case 3, 4:
+            case openCount >= 3:
                 stats.Junctions++
             }
         }
@@ -252,28 +423,69 @@ func (m *Maze) CalculateStats() MazeStats {
         stats.Complexity = branchingFactor * scaleBonus
     }
 
+    stats.Loops = m.countLoops()
     return stats
 }
 
-func (m *Maze) SyncGridToWeights(original map[string]int) {
-    m.Weights = make(map[string]int)
-    for r := 0; r < m.Rows; r++ {
-        for c := 0; c < m.Cols; c++ {
-            
-            keyTop := fmt.Sprintf("%d-%d-top", r, c)
-            m.Weights[keyTop] = m.getWeightForWall(r, c, 0, keyTop, original)
-
-            keyLeft := fmt.Sprintf("%d-%d-left", r, c)
-            m.Weights[keyLeft] = m.getWeightForWall(r, c, 3, keyLeft, original)
-
-            if r == m.Rows-1 {
-                keyBottom := fmt.Sprintf("%d-%d-bottom", r, c)
-                m.Weights[keyBottom] = m.getWeightForWall(r, c, 2, keyBottom, original)
+// countLoops returns the number of independent cycles in the passage graph
+// (edges - cells + components), which is zero for a perfect maze.
+func (m *Maze) countLoops() int {
+    dsu := NewDSU(m.cellCount())
+    edges, cells := 0, 0
+
+    for r := range m.Grid {
+        for c := range m.Grid[r] {
+            if m.Grid[r][c].Masked {
+                continue
+            }
+            cells++
+        }
+    }
+    components := cells
+
+    for r := range m.Grid {
+        for c := range m.Grid[r] {
+            if m.Grid[r][c].Masked {
+                continue
+            }
+            id := m.index(r, c)
+            for side := range m.Grid[r][c].Walls {
+                nr, nc, ok := m.passage(r, c, side)
+                if !ok {
+                    continue
+                }
+                // count each passage once, from its lower-numbered cell
+                nid := m.index(nr, nc)
+                if nid < id {
+                    continue
+                }
+                edges++
+                if dsu.Find(id) != dsu.Find(nid) {
+                    dsu.Union(id, nid)
+                    components--
+                }
             }
+        }
+    }
 
-            if c == m.Cols-1 {
-                keyRight := fmt.Sprintf("%d-%d-right", r, c)
-                m.Weights[keyRight] = m.getWeightForWall(r, c, 1, keyRight, original)
+    return edges - cells + components
+}
+
+func (m *Maze) SyncGridToWeights(original map[string]int) {
+    m.Weights = make(map[string]int)
+    for r := range m.Grid {
+        for c := range m.Grid[r] {
+            // shared walls are recorded once, by the cell that owns the key,
+            // unless the two sides disagree and each needs its own weight
+            for side := range m.Grid[r][c].Walls {
+                key := m.weightKey(r, c, side)
+                if !m.ownsWall(r, c, side) {
+                    if !m.splitWall(r, c, side) {
+                        continue
+                    }
+                    key = m.sideKey(r, c, side)
+                }
+                m.Weights[key] = m.getWeightForWall(r, c, side, key, original)
             }
         }
     }
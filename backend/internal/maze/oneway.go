@@ -0,0 +1,73 @@
+package maze
+
+import "math/rand/v2"
+
+// One-way passages can be walked in only one direction. Walls stay
+// symmetric; a cell's OneWay bits mark the open sides it cannot be left
+// through, so the passage behind such a side only leads into the cell.
+
+// canExit reports whether a passage through side may be taken out of
+// (r, c).
+func (m *Maze) canExit(r, c, side int) bool {
+	return m.Grid[r][c].OneWay&(1<<side) == 0
+}
+
+// AddOneWays turns roughly fraction of the open passages into one-way
+// ones, from 0 to 1, while keeping End reachable from Start. Start and End
+// must already be placed. It returns the number of passages turned.
+func (m *Maze) AddOneWays(fraction float64) int {
+	return m.addOneWays(fraction, m.Rand())
+}
+
+func (m *Maze) addOneWays(fraction float64, rng *rand.Rand) int {
+	// passages on one route to the end may only point along it
+	forward := map[[2]Point]bool{}
+	_, route := m.SolveBFS()
+	for i := 1; i < len(route); i++ {
+		forward[[2]Point{Point(route[i-1]), Point(route[i])}] = true
+	}
+
+	// stairs stay two-way, so every arrow has a wall to be drawn on
+	sides := m.floorTopo().Sides()
+	turned := 0
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if !m.isActive(r, c) {
+				continue
+			}
+			from := Point{r, c}
+			for side := range sides {
+				nr, nc, ok := m.passage(r, c, side)
+				to := Point{nr, nc}
+				// visit each passage once, from its lower-numbered cell
+				if !ok || m.index(nr, nc) < m.index(r, c) || rng.Float64() >= fraction {
+					continue
+				}
+				back := m.sideTowardsPassage(to, from)
+				if back < 0 || !m.canExit(r, c, side) || !m.canExit(nr, nc, back) {
+					continue
+				}
+
+				// block the way back for from -> to, or the way forward
+				a, aSide := to, back
+				if forward[[2]Point{to, from}] || (!forward[[2]Point{from, to}] && rng.IntN(2) == 0) {
+					a, aSide = from, side
+				}
+				m.Grid[a[0]][a[1]].OneWay |= 1 << aSide
+				turned++
+			}
+		}
+	}
+	return turned
+}
+
+// sideTowardsPassage returns the side of p whose passage leads to q, or
+// -1 if none does.
+func (m *Maze) sideTowardsPassage(p, q Point) int {
+	for side := range m.Grid[p[0]][p[1]].Walls {
+		if nr, nc, ok := m.passage(p[0], p[1], side); ok && nr == q[0] && nc == q[1] {
+			return side
+		}
+	}
+	return -1
+}
@@ -0,0 +1,57 @@
+package maze
+
+import "testing"
+
+// ringMaze is a 2x2 loop whose top passage can only be walked leftwards.
+func ringMaze(t *testing.T, start, end Point) *Maze {
+	t.Helper()
+	m := carvedMaze(t, 2, 2, start, end,
+		[2]Point{{0, 0}, {0, 1}}, [2]Point{{0, 1}, {1, 1}}, [2]Point{{1, 1}, {1, 0}}, [2]Point{{1, 0}, {0, 0}})
+	m.Grid[0][0].OneWay = 1 << 1 // no leaving (0, 0) to the right
+	return m
+}
+
+func TestSolveOneWay(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end Point
+		want       int
+	}{
+		{"blocks the direct route", Point{0, 0}, Point{0, 1}, 4},
+		{"walked forwards", Point{0, 1}, Point{0, 0}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkSolvers(t, ringMaze(t, tt.start, tt.end), tt.want)
+		})
+	}
+}
+
+func TestAddOneWaysKeepsEndReachable(t *testing.T) {
+	for seed := range int64(10) {
+		m := braidedMaze(t, 20, 20, seed, 0.5)
+		if m.AddOneWays(0.5) == 0 {
+			t.Fatalf("seed %d: no passages turned", seed)
+		}
+		if _, path := m.SolveBFS(); len(path) == 0 {
+			t.Errorf("seed %d: end cut off by one-way passages", seed)
+		}
+	}
+}
+
+func TestOneWaysRoundTrip(t *testing.T) {
+	m := braidedMaze(t, 20, 20, 7, 0.5)
+	m.AddOneWays(0.3)
+
+	restored := NewMaze(m.Rows, m.Cols)
+	if err := restored.DecodeFeatures(m.EncodeFeatures()); err != nil {
+		t.Fatal(err)
+	}
+	for r := range m.Grid {
+		for c, cell := range m.Grid[r] {
+			if got := restored.Grid[r][c].OneWay; got != cell.OneWay {
+				t.Errorf("cell (%d, %d) restored with one-way sides %b, want %b", r, c, got, cell.OneWay)
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package maze
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Placement strategies choose where the entrance and exit go.
+const (
+	PlaceRandom   = "random"   // border cells far apart on the grid
+	PlaceFarthest = "farthest" // border cells farthest apart along the passages
+	PlaceMinPath  = "min_path" // random border cells at least MinPath apart along the passages
+	PlaceInterior = "interior" // entrance anywhere inside, exit on the border farthest from it
+	PlaceCorners  = "corners"  // the top-left and bottom-right corners
+)
+
+// ErrUnknownPlacement is returned for a placement strategy that does not
+// exist or cannot be used on the maze's grid.
+var ErrUnknownPlacement = errors.New("unknown placement strategy")
+
+// Placement selects how SetStartEnd chooses the entrance and exit.
+type Placement struct {
+	Strategy string `json:"strategy"`
+	// MinPath is the fewest moves between entrance and exit for PlaceMinPath.
+	// Zero asks for half the grid's rows plus columns.
+	MinPath int `json:"min_path,omitempty"`
+}
+
+// Validate checks the strategy is known and MinPath is not negative.
+func (p Placement) Validate() error {
+	switch p.Strategy {
+	case "", PlaceRandom, PlaceFarthest, PlaceMinPath, PlaceInterior, PlaceCorners:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownPlacement, p.Strategy)
+	}
+	if p.MinPath < 0 {
+		return errors.New("min_path must not be negative")
+	}
+	return nil
+}
+
+// SetStartEnd places the entrance and exit of a generated maze using p,
+// opening their outer walls. An empty strategy is PlaceRandom.
+func (m *Maze) SetStartEnd(p Placement) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	rng := m.Rand()
+
+	var start, end Point
+	switch p.Strategy {
+	case "", PlaceRandom:
+		m.SetRandomStartEnd()
+		m.Placement = PlaceRandom
+		return nil
+	case PlaceFarthest:
+		start, end = m.farthestPair(m.entrances(), rng)
+	case PlaceMinPath:
+		minPath := p.MinPath
+		if minPath == 0 {
+			minPath = (m.Rows + m.Cols) / 2
+		}
+		start, end = m.minPathPair(minPath, rng)
+	case PlaceInterior:
+		var inside []Point
+		outside := m.exterior()
+		for r := range m.Grid {
+			for c := range m.Grid[r] {
+				if m.isActive(r, c) && !m.isBorder(r, c, outside) {
+					inside = append(inside, Point{r, c})
+				}
+			}
+		}
+		if len(inside) == 0 {
+			return fmt.Errorf("%w: %s grid has no interior cells", ErrUnknownPlacement, m.layout())
+		}
+		start = inside[rng.IntN(len(inside))]
+		end = m.farthestFrom(start, m.entrances())
+	case PlaceCorners:
+		if _, ok := m.shape().(polarTopology); ok {
+			return fmt.Errorf("%w: %s grids have no corners", ErrUnknownPlacement, m.Topology)
+		}
+		last := len(m.Grid) - 1
+		start = m.nearestActive(Point{0, 0})
+		end = m.nearestActive(Point{last, len(m.Grid[last]) - 1})
+	}
+
+	m.Start, m.End = start, end
+	m.Placement = p.Strategy
+	m.clipBorderWall(start[0], start[1])
+	m.clipBorderWall(end[0], end[1])
+	return nil
+}
+
+// entrances lists the cells an entrance or exit may open from: the border
+// of the maze, or every cell of a torus, which has none.
+func (m *Maze) entrances() []Point {
+	var border, all []Point
+	outside := m.exterior()
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if !m.isActive(r, c) {
+				continue
+			}
+			all = append(all, Point{r, c})
+			if m.isBorder(r, c, outside) {
+				border = append(border, Point{r, c})
+			}
+		}
+	}
+	if len(border) == 0 {
+		return all
+	}
+	return border
+}
+
+// pathDistances returns the number of moves from p to every cell along the
+// passages, indexed like the grid, with -1 for cells it cannot reach.
+func (m *Maze) pathDistances(p Point) []int {
+	dist := make([]int, m.cellCount())
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[m.index(p[0], p[1])] = 0
+	for queue := []Point{p}; len(queue) > 0; queue = queue[1:] {
+		cur := queue[0]
+		d := dist[m.index(cur[0], cur[1])]
+		for _, next := range m.GetNeighbors(cur) {
+			if i := m.index(next[0], next[1]); dist[i] < 0 {
+				dist[i] = d + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return dist
+}
+
+// farthestFrom returns the candidate farthest from p along the passages,
+// or p itself if it reaches none of them.
+func (m *Maze) farthestFrom(p Point, candidates []Point) Point {
+	dist := m.pathDistances(p)
+	best, bestDist := p, 0
+	for _, q := range candidates {
+		if d := dist[m.index(q[0], q[1])]; d > bestDist {
+			best, bestDist = q, d
+		}
+	}
+	return best
+}
+
+// farthestPair finds two candidates far apart along the passages with a
+// double breadth-first search: the candidate farthest from a random one,
+// then the candidate farthest from that. On a perfect maze whose
+// candidates are all its cells this is exactly the diameter.
+func (m *Maze) farthestPair(candidates []Point, rng *rand.Rand) (Point, Point) {
+	start := m.farthestFrom(candidates[rng.IntN(len(candidates))], candidates)
+	return start, m.farthestFrom(start, candidates)
+}
+
+// minPathPair picks a random entrance and a random exit at least minPath
+// moves from it. After a few entrances with no such exit it settles for
+// the farthest pair.
+func (m *Maze) minPathPair(minPath int, rng *rand.Rand) (Point, Point) {
+	candidates := m.entrances()
+	for range 20 {
+		start := candidates[rng.IntN(len(candidates))]
+		dist := m.pathDistances(start)
+		var far []Point
+		for _, q := range candidates {
+			if dist[m.index(q[0], q[1])] >= minPath {
+				far = append(far, q)
+			}
+		}
+		if len(far) > 0 {
+			return start, far[rng.IntN(len(far))]
+		}
+	}
+	return m.farthestPair(candidates, rng)
+}
+
+// nearestActive returns p, or the active cell closest to it if p is
+// masked.
+func (m *Maze) nearestActive(p Point) Point {
+	if m.isActive(p[0], p[1]) {
+		return p
+	}
+	best, bestDist := p, -1
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if !m.isActive(r, c) {
+				continue
+			}
+			if d := m.distance(p, Point{r, c}); bestDist < 0 || d < bestDist {
+				best, bestDist = Point{r, c}, d
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,121 @@
+package maze
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Portals join two cells that need not be adjacent. Stepping onto either
+// end of a pair lets the player move to the other in a single move, in
+// either direction, on top of the cell's usual passages.
+
+// MaxPortals is the most portal pairs a maze may hold.
+const MaxPortals = 8
+
+// Portal is a pair of cells joined by a portal.
+type Portal [2]Point
+
+// portalExit returns the other end of the portal at p, if p holds one.
+func (m *Maze) portalExit(p Point) (Point, bool) {
+	for _, portal := range m.Portals {
+		switch p {
+		case portal[0]:
+			return portal[1], true
+		case portal[1]:
+			return portal[0], true
+		}
+	}
+	return Point{}, false
+}
+
+// PlacePortals adds up to n portal pairs, each joining two cells far apart
+// along the passages. Start and End must already be placed and are never
+// given a portal. It returns the number of pairs placed.
+func (m *Maze) PlacePortals(n int) int {
+	return m.placePortals(min(n, MaxPortals-len(m.Portals)), m.Rand())
+}
+
+func (m *Maze) placePortals(n int, rng *rand.Rand) int {
+	var candidates []Point
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			p := Point{r, c}
+			if !m.isActive(r, c) || m.Grid[r][c].Tunnel || p == Point(m.Start) || p == Point(m.End) {
+				continue
+			}
+			if _, taken := m.portalExit(p); !taken {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+
+	placed := 0
+	for ; placed < n && len(candidates) > 1; placed++ {
+		i := rng.IntN(len(candidates))
+		a := candidates[i]
+		candidates = append(candidates[:i], candidates[i+1:]...)
+
+		// the other end goes among the candidates in the farther half from
+		// a, never right beside it
+		dist := m.pathDistances(a)
+		farthest := 0
+		for _, q := range candidates {
+			farthest = max(farthest, dist[m.index(q[0], q[1])])
+		}
+		var far []int
+		for j, q := range candidates {
+			if d := dist[m.index(q[0], q[1])]; d >= (farthest+1)/2 && m.distance(a, q) > 1 {
+				far = append(far, j)
+			}
+		}
+		if len(far) == 0 {
+			break
+		}
+		j := far[rng.IntN(len(far))]
+		m.Portals = append(m.Portals, Portal{a, candidates[j]})
+		candidates = append(candidates[:j], candidates[j+1:]...)
+	}
+	return placed
+}
+
+// EncodePortals lists the maze's portal pairs as JSON, or returns an empty
+// string if it has none.
+func (m *Maze) EncodePortals() string {
+	if len(m.Portals) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(m.Portals)
+	return string(data)
+}
+
+// DecodePortals restores the portal pairs produced by EncodePortals.
+func (m *Maze) DecodePortals(data string) error {
+	var portals []Portal
+	if err := json.Unmarshal([]byte(data), &portals); err != nil {
+		return fmt.Errorf("decode portals: %w", err)
+	}
+	if err := m.validatePortals(portals); err != nil {
+		return fmt.Errorf("decode portals: %w", err)
+	}
+	m.Portals = portals
+	return nil
+}
+
+// validatePortals checks that there are at most MaxPortals pairs and that
+// every end is a distinct active cell.
+func (m *Maze) validatePortals(portals []Portal) error {
+	if len(portals) > MaxPortals {
+		return fmt.Errorf("more than %d portal pairs", MaxPortals)
+	}
+	seen := map[Point]bool{}
+	for _, portal := range portals {
+		for _, p := range portal {
+			if !m.isActive(p[0], p[1]) || seen[p] {
+				return fmt.Errorf("invalid portal at (%d, %d)", p[0], p[1])
+			}
+			seen[p] = true
+		}
+	}
+	return nil
+}
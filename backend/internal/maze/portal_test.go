@@ -0,0 +1,68 @@
+package maze
+
+import (
+	"slices"
+	"testing"
+)
+
+// corridor is a single row of cols cells joined end to end.
+func corridor(t *testing.T, cols int) *Maze {
+	t.Helper()
+	var links [][2]Point
+	for c := 1; c < cols; c++ {
+		links = append(links, [2]Point{{0, c - 1}, {0, c}})
+	}
+	return carvedMaze(t, 1, cols, Point{0, 0}, Point{0, cols - 1}, links...)
+}
+
+func TestSolvePortal(t *testing.T) {
+	tests := []struct {
+		name    string
+		portals []Portal
+		want    int
+	}{
+		{"no portal", nil, 6},
+		{"portal shortcut", []Portal{{{0, 1}, {0, 4}}}, 4},
+		{"portal entered from either end", []Portal{{{0, 4}, {0, 1}}}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := corridor(t, 6)
+			m.Portals = tt.portals
+			checkSolvers(t, m, tt.want)
+		})
+	}
+}
+
+func TestPortalsRoundTrip(t *testing.T) {
+	m := braidedMaze(t, 20, 20, 7, 0.5)
+	if m.PlacePortals(3) == 0 {
+		t.Fatal("no portals placed")
+	}
+
+	restored := NewMaze(m.Rows, m.Cols)
+	if err := restored.DecodePortals(m.EncodePortals()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(restored.Portals, m.Portals) {
+		t.Errorf("portals restored as %v, want %v", restored.Portals, m.Portals)
+	}
+}
+
+func TestDecodePortalsRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		portals string
+	}{
+		{"portal off the grid", `[[[0,0],[50,50]]]`},
+		{"portal ends shared", `[[[0,0],[1,1]],[[1,1],[2,2]]]`},
+		{"too many portals", `[[[0,0],[0,1]],[[0,2],[0,3]],[[1,0],[1,1]],[[1,2],[1,3]],[[2,0],[2,1]],[[2,2],[2,3]],[[3,0],[3,1]],[[3,2],[3,3]],[[0,4],[1,4]]]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewMaze(4, 5).DecodePortals(tt.portals); err == nil {
+				t.Error("decoded without error")
+			}
+		})
+	}
+}
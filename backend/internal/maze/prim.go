@@ -0,0 +1,90 @@
+package maze
+
+import (
+	"container/heap"
+	"math/rand/v2"
+)
+
+// wallHeap orders frontier walls by priority for Prim's algorithm.
+type wallHeap []Wall
+
+func (h wallHeap) Len() int           { return len(h) }
+func (h wallHeap) Less(i, j int) bool { return h[i].Weight < h[j].Weight }
+func (h wallHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *wallHeap) Push(x interface{}) {
+	*h = append(*h, x.(Wall))
+}
+func (h *wallHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	*h = old[:n-1]
+	return w
+}
+
+// GeneratePrim triggers a randomized Prim's generation. Growing a single
+// tree from its frontier leaves many short, bushy dead ends.
+func (m *Maze) GeneratePrim() {
+	m.generateWeightedPrim(nil, m.Rand())
+}
+
+// GenerateImagePrim triggers a guided Prim's generation using weights
+// derived from an image.
+func (m *Maze) GenerateImagePrim(weights map[string]int) {
+	m.Weights = weights
+	m.generateWeightedPrim(weights, m.Rand())
+}
+
+// generateWeightedPrim grows a spanning tree from a random cell, always
+// carving the lowest priority wall on the frontier next.
+func (m *Maze) generateWeightedPrim(edgeWeights map[string]int, rng *rand.Rand) {
+	m.setupWallWeights(edgeWeights)
+	if m.Rows == 0 || m.Cols == 0 {
+		return
+	}
+
+	inTree := make([]bool, m.cellCount())
+	frontier := &wallHeap{}
+	isImageMode := edgeWeights != nil
+
+	addCell := func(r, c int) {
+		inTree[m.index(r, c)] = true
+		m.trace1(TraceVisit, [2]int{r, c})
+		for side := range m.Grid[r][c].Walls {
+			nr, nc, _ := m.neighbor(r, c, side)
+			if !m.isActive(nr, nc) || inTree[m.index(nr, nc)] {
+				continue
+			}
+			w := Wall{R1: r, C1: c, R2: nr, C2: nc}
+			w.Weight = wallWeight(edgeWeights, m.weightKey(r, c, side), rng)
+			if isImageMode {
+				m.recordWallWeight(w)
+			}
+			heap.Push(frontier, w)
+		}
+	}
+
+	grow := func() {
+		for frontier.Len() > 0 {
+			w := heap.Pop(frontier).(Wall)
+			if inTree[m.index(w.R2, w.C2)] {
+				continue
+			}
+			m.RemoveWalls(w.R1, w.C1, w.R2, w.C2)
+			addCell(w.R2, w.C2)
+		}
+	}
+
+	addCell(m.randomActiveCell(rng))
+	grow()
+
+	// separate pieces of a masked shape each grow their own tree
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if m.isActive(r, c) && !inTree[m.index(r, c)] {
+				addCell(r, c)
+				grow()
+			}
+		}
+	}
+}
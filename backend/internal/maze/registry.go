@@ -0,0 +1,370 @@
+package maze
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+)
+
+// ErrUnknownGenerator is returned when no generator is registered under
+// the requested name.
+var ErrUnknownGenerator = errors.New("unknown generator")
+
+// ErrUnsupportedTopology is returned when a generator cannot build a maze
+// on the requested grid topology.
+var ErrUnsupportedTopology = errors.New("generator does not support this topology")
+
+// Param describes one tunable input of a generator, so clients can build
+// their controls from the server instead of hard-coding them.
+type Param struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // int, float, string or file
+	Default     string   `json:"default,omitempty"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+	Description string   `json:"description"`
+}
+
+// Params holds the raw values supplied for a generator's parameters.
+type Params map[string]string
+
+// Int reads an integer parameter, returning def when it is absent.
+func (p Params) Int(name string, def int) (int, error) {
+	v, ok := p[name]
+	if !ok || v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
+
+// Float reads a float parameter, returning def when it is absent.
+func (p Params) Float(name string, def float64) (float64, error) {
+	v, ok := p[name]
+	if !ok || v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return f, nil
+}
+
+// Generator builds a maze on a fully walled grid. Implementations draw all
+// randomness from rng so a seed reproduces the same maze.
+type Generator interface {
+	Name() string
+	Description() string
+	Params() []Param
+	Generate(ctx context.Context, m *Maze, rng *rand.Rand) error
+}
+
+// GeneratorFactory configures a generator from request parameters. It must
+// accept an empty Params and fall back to defaults.
+type GeneratorFactory func(p Params) (Generator, error)
+
+var (
+	generatorFactories = map[string]GeneratorFactory{}
+	generatorOrder     []string
+)
+
+// RegisterGenerator makes a generator available under name.
+func RegisterGenerator(name string, factory GeneratorFactory) {
+	if _, exists := generatorFactories[name]; exists {
+		panic("maze: generator registered twice: " + name)
+	}
+	generatorFactories[name] = factory
+	generatorOrder = append(generatorOrder, name)
+}
+
+// NewGenerator returns the generator registered under name, configured
+// with p.
+func NewGenerator(name string, p Params) (Generator, error) {
+	factory, ok := generatorFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownGenerator, name)
+	}
+	return factory(p)
+}
+
+// Generators lists every registered generator with default parameters,
+// in registration order.
+func Generators() []Generator {
+	gens := make([]Generator, 0, len(generatorOrder))
+	for _, name := range generatorOrder {
+		if g, err := generatorFactories[name](Params{}); err == nil {
+			gens = append(gens, g)
+		}
+	}
+	return gens
+}
+
+// funcGenerator adapts a plain function to the Generator interface.
+type funcGenerator struct {
+	name        string
+	description string
+	params      []Param
+	squareOnly  bool // relies on square cells, e.g. for image pixels or straight walls
+	generate    func(ctx context.Context, m *Maze, rng *rand.Rand) error
+}
+
+func (g *funcGenerator) Name() string        { return g.name }
+func (g *funcGenerator) Description() string { return g.description }
+func (g *funcGenerator) Params() []Param     { return g.params }
+
+func (g *funcGenerator) Generate(ctx context.Context, m *Maze, rng *rand.Rand) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if g.squareOnly && !m.IsSquare() {
+		return fmt.Errorf("%w: %s on %s", ErrUnsupportedTopology, g.name, m.layout())
+	}
+	return g.generate(ctx, m, rng)
+}
+
+// Bound returns a pointer for use as a Param minimum or maximum.
+func Bound(v float64) *float64 { return &v }
+
+var imageParam = Param{
+	Name:        "image",
+	Type:        "file",
+	Description: "Source image whose edges are kept as walls.",
+}
+
+// biasParams are the directional bias inputs shared by Kruskal's algorithm
+// and the backtracker.
+var biasParams = []Param{
+	{Name: "bias", Type: "float", Default: "0.5", Min: Bound(0), Max: Bound(1), Description: "Preference for passages along rows over passages between them."},
+	{Name: "river", Type: "float", Default: "0", Min: Bound(0), Max: Bound(1), Description: "How strongly passages keep going straight, giving long corridors."},
+}
+
+// parseBias reads biasParams, falling back to no bias.
+func parseBias(p Params) (BiasOptions, error) {
+	opts := DefaultBiasOptions
+	var err error
+	if opts.Horizontal, err = p.Float("bias", opts.Horizontal); err != nil {
+		return opts, err
+	}
+	if opts.River, err = p.Float("river", opts.River); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+func init() {
+	RegisterGenerator("image", func(p Params) (Generator, error) {
+		return &funcGenerator{
+			name:        "image",
+			description: "Kruskal's algorithm guided by the edges of an uploaded image.",
+			params:      []Param{imageParam},
+			squareOnly:  true,
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				if m.Weights == nil {
+					return errors.New("image generator requires an image")
+				}
+				m.generateWeightedKruskal(m.Weights, DefaultBiasOptions, rng)
+				return nil
+			},
+		}, nil
+	})
+
+	RegisterGenerator("kruskal", func(p Params) (Generator, error) {
+		bias, err := parseBias(p)
+		if err != nil {
+			return nil, err
+		}
+		return &funcGenerator{
+			name:        "kruskal",
+			description: "Randomized Kruskal's algorithm merging cells in random wall order.",
+			params:      biasParams,
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				m.generateWeightedKruskal(nil, bias, rng)
+				return nil
+			},
+		}, nil
+	})
+
+	RegisterGenerator("recursive", func(p Params) (Generator, error) {
+		startRow, err := p.Int("start_row", 0)
+		if err != nil {
+			return nil, err
+		}
+		startCol, err := p.Int("start_col", 0)
+		if err != nil {
+			return nil, err
+		}
+		bias, err := parseBias(p)
+		if err != nil {
+			return nil, err
+		}
+		return &funcGenerator{
+			name:        "recursive",
+			description: "Depth-first recursive backtracker producing long, winding corridors.",
+			params: append([]Param{
+				{Name: "start_row", Type: "int", Default: "0", Min: Bound(0), Description: "Row the backtracker starts from."},
+				{Name: "start_col", Type: "int", Default: "0", Min: Bound(0), Description: "Column the backtracker starts from."},
+			}, biasParams...),
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				return m.generateRecursive(startRow, startCol, bias, rng)
+			},
+		}, nil
+	})
+
+	RegisterGenerator("prim", func(p Params) (Generator, error) {
+		return &funcGenerator{
+			name:        "prim",
+			description: "Randomized Prim's algorithm with short, bushy dead ends. Optionally guided by an image.",
+			params:      []Param{imageParam},
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				if m.Weights != nil && !m.IsSquare() {
+					return fmt.Errorf("%w: image-guided prim on %s", ErrUnsupportedTopology, m.layout())
+				}
+				m.generateWeightedPrim(m.Weights, rng)
+				return nil
+			},
+		}, nil
+	})
+
+	RegisterGenerator("wilson", func(p Params) (Generator, error) {
+		return &funcGenerator{
+			name:        "wilson",
+			description: "Wilson's loop-erased random walks, sampling uniformly from all spanning trees.",
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				return m.generateWilson(ctx, rng)
+			},
+		}, nil
+	})
+
+	RegisterGenerator("aldous-broder", func(p Params) (Generator, error) {
+		return &funcGenerator{
+			name:        "aldous-broder",
+			description: "Aldous-Broder random walk, sampling uniformly from all spanning trees.",
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				return m.generateAldousBroder(ctx, rng)
+			},
+		}, nil
+	})
+
+	RegisterGenerator("growing-tree", func(p Params) (Generator, error) {
+		strategy := DefaultGrowingTreeStrategy
+		if v := p["strategy"]; v != "" {
+			parsed, err := ParseGrowingTreeStrategy(v)
+			if err != nil {
+				return nil, err
+			}
+			strategy = parsed
+		}
+		return &funcGenerator{
+			name:        "growing-tree",
+			description: "Growing Tree with a tunable mix between backtracker and Prim's textures.",
+			params: []Param{
+				{Name: "strategy", Type: "string", Default: "newest:75,random:25", Description: "Weighted mix of newest, oldest and random cell selection."},
+			},
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				m.generateGrowingTree(strategy, rng)
+				return nil
+			},
+		}, nil
+	})
+
+	RegisterGenerator("division", func(p Params) (Generator, error) {
+		opts := DefaultDivisionOptions
+		minChamber, err := p.Int("min_chamber", opts.MinChamber)
+		if err != nil {
+			return nil, err
+		}
+		if minChamber < 1 {
+			return nil, errors.New("min_chamber must be at least 1")
+		}
+		bias, err := p.Float("bias", opts.HorizontalBias)
+		if err != nil {
+			return nil, err
+		}
+		if bias < 0 || bias > 1 {
+			return nil, errors.New("bias must be between 0 and 1")
+		}
+		opts.MinChamber, opts.HorizontalBias = minChamber, bias
+
+		return &funcGenerator{
+			name:        "division",
+			description: "Recursive division adding walls to an open field, giving long corridors and rooms.",
+			params: []Param{
+				{Name: "min_chamber", Type: "int", Default: "1", Min: Bound(1), Description: "Smallest chamber a split may leave; larger values leave rooms."},
+				{Name: "bias", Type: "float", Default: "0.5", Min: Bound(0), Max: Bound(1), Description: "Preference for horizontal over vertical walls."},
+			},
+			squareOnly: true,
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				m.generateDivision(opts, rng)
+				return nil
+			},
+		}, nil
+	})
+
+	RegisterGenerator("weave", func(p Params) (Generator, error) {
+		density, err := p.Float("density", DefaultWeaveDensity)
+		if err != nil {
+			return nil, err
+		}
+		if density < 0 || density > 1 {
+			return nil, errors.New("density must be between 0 and 1")
+		}
+		return &funcGenerator{
+			name:        "weave",
+			description: "Kruskal's algorithm around random crossings where passages run under one another.",
+			params: []Param{
+				{Name: "density", Type: "float", Default: "0.3", Min: Bound(0), Max: Bound(1), Description: "Chance that an interior cell becomes a crossing."},
+			},
+			squareOnly: true,
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				m.generateWeave(density, rng)
+				return nil
+			},
+		}, nil
+	})
+
+	RegisterGenerator("dungeon", func(p Params) (Generator, error) {
+		opts := DefaultDungeonOptions
+		var err error
+		if opts.Rooms, err = p.Int("rooms", opts.Rooms); err != nil {
+			return nil, err
+		}
+		if opts.MinRoom, err = p.Int("min_room", opts.MinRoom); err != nil {
+			return nil, err
+		}
+		if opts.MaxRoom, err = p.Int("max_room", opts.MaxRoom); err != nil {
+			return nil, err
+		}
+		if opts.Doors, err = p.Int("doors", opts.Doors); err != nil {
+			return nil, err
+		}
+		if opts.Prune, err = p.Float("prune", opts.Prune); err != nil {
+			return nil, err
+		}
+		if err := opts.Validate(); err != nil {
+			return nil, err
+		}
+
+		return &funcGenerator{
+			name:        "dungeon",
+			description: "Open rooms joined by doors to a maze of corridors, for roguelike levels.",
+			params: []Param{
+				{Name: "rooms", Type: "int", Default: "8", Min: Bound(0), Description: "Rooms to try to place; fewer fit on small grids."},
+				{Name: "min_room", Type: "int", Default: "3", Min: Bound(1), Description: "Smallest width or height of a room."},
+				{Name: "max_room", Type: "int", Default: "6", Min: Bound(1), Description: "Largest width or height of a room."},
+				{Name: "doors", Type: "int", Default: "2", Min: Bound(1), Description: "Most doors a room may have; rooms with more than one add loops."},
+				{Name: "prune", Type: "float", Default: "0", Min: Bound(0), Max: Bound(1), Description: "Chance that each dead-end corridor is filled in, leaving solid rock."},
+			},
+			squareOnly: true,
+			generate: func(ctx context.Context, m *Maze, rng *rand.Rand) error {
+				m.generateDungeon(opts, rng)
+				return nil
+			},
+		}, nil
+	})
+}
@@ -6,16 +6,33 @@ import (
 	"image/draw"
 	"image/png"
 	"io"
+	"math"
 	"runtime"
 	"sync"
 )
 
-// prepareCanvas initializes the image buffer and background.
-func (m *Maze) prepareCanvas(cellSize int) *image.RGBA {
+// floorSize returns the pixel size of one floor of the maze.
+func (m *Maze) floorSize(cellSize int) (int, int) {
 	// add 1 pixel to the total width/height to ensure
 	// closing edges of the rightmost and bottommost cells are rendered
-	imgWidth := m.Cols*cellSize + 1
-	imgHeight := m.Rows*cellSize + 1
+	switch m.shape().(type) {
+	case hexTopology:
+		hex := newHexGeometry(cellSize)
+		return int(math.Ceil(hex.width*(float64(m.Cols)+0.5))) + 1,
+			int(math.Ceil(hex.radius*(1.5*float64(m.Rows)+0.5))) + 1
+	case polarTopology:
+		return 2*m.Rows*cellSize + 1, 2*m.Rows*cellSize + 1
+	}
+	return m.Cols*cellSize + 1, m.Rows*cellSize + 1
+}
+
+// prepareCanvas initializes the image buffer and background. Floors of a
+// multi-level maze sit side by side, one cell apart.
+func (m *Maze) prepareCanvas(cellSize int) *image.RGBA {
+	imgWidth, imgHeight := m.floorSize(cellSize)
+	if m.Levels > 1 {
+		imgWidth = m.Levels*imgWidth + (m.Levels-1)*cellSize
+	}
 	img := image.NewRGBA(image.Rect(0, 0, imgWidth, imgHeight))
 
 	// initialise white background
@@ -33,8 +50,25 @@ func (m *Maze) RenderToWriter(w io.Writer, cellSize int) error {
 
 // drawMaze iterates through the grid and paints each active wall.
 func (m *Maze) drawMaze(img *image.RGBA, cellSize int) {
+	if m.Levels > 1 {
+		m.drawLevels(img, cellSize)
+		return
+	}
 	var wg sync.WaitGroup
 
+	drawCell := m.drawSquareCell
+	if m.HasTunnels() {
+		drawCell = m.drawWeaveCell
+	}
+	switch m.shape().(type) {
+	case hexTopology:
+		hex := newHexGeometry(cellSize)
+		drawCell = func(img *image.RGBA, r, c, _ int) { m.drawHexCell(img, r, c, hex) }
+	case polarTopology:
+		drawCell = m.drawPolarCell
+	}
+	m.rowOffsets() // build lazily cached state before sharing m across workers
+
 	numCPU := runtime.NumCPU()
 	rowsPerWorker := m.Rows / numCPU
 
@@ -57,38 +91,517 @@ func (m *Maze) drawMaze(img *image.RGBA, cellSize int) {
 		go func(rMin, rMax int) {
 			defer wg.Done()
 			for r := rMin; r < rMax; r++ {
-				for c := 0; c < m.Cols; c++ {
-					x := c * cellSize
-					y := r * cellSize
-					cell := m.Grid[r][c]
-
-					if r == m.Start[0] && c == m.Start[1] {
-						m.fillCell(img, x, y, cellSize, color.RGBA{144, 238, 144, 255}) // Light Green
-					} else if r == m.End[0] && c == m.End[1] {
-						m.fillCell(img, x, y, cellSize, color.RGBA{255, 99, 71, 255}) // Red
-					}
-
-					// TOP WALL
-					if cell.Walls[0] {
-						m.paintWall(img, x, y, cellSize, 0, cell.WallWeights[0])
-					}
-					// RIGHT WALL
-					if cell.Walls[1] {
-						m.paintWall(img, x, y, cellSize, 1, cell.WallWeights[1])
-					}
-					// BOTTOM WALL
-					if cell.Walls[2] {
-						m.paintWall(img, x, y, cellSize, 2, cell.WallWeights[2])
-					}
-					// LEFT WALL
-					if cell.Walls[3] {
-						m.paintWall(img, x, y, cellSize, 3, cell.WallWeights[3])
+				for c := range m.Grid[r] {
+					if !m.Grid[r][c].Masked {
+						drawCell(img, r, c, cellSize)
 					}
 				}
 			}
 		}(startY, endY)
 	}
 	wg.Wait()
+
+	if m.Wrap != WrapNone {
+		m.drawWrapMarkers(img, cellSize)
+	}
+	m.drawOneWays(img, cellSize)
+	m.drawKeysAndDoors(img, cellSize)
+	m.drawPortals(img, cellSize)
+}
+
+// drawOneWays points an arrow along every one-way passage, on the wall it
+// crosses into the cell it leads to.
+func (m *Maze) drawOneWays(img *image.RGBA, cellSize int) {
+	for r := range m.Grid {
+		for c, cell := range m.Grid[r] {
+			if cell.Masked || cell.OneWay == 0 {
+				continue
+			}
+			for side, isWall := range cell.Walls {
+				if isWall || m.canExit(r, c, side) {
+					continue
+				}
+				nr, nc, crosses := m.wraps(r, c, side)
+				if !crosses {
+					nr, nc, _ = m.neighbor(r, c, side)
+				}
+				cx, cy := m.cellCentre(r, c, cellSize)
+				nx, ny := m.cellCentre(nr, nc, cellSize)
+				drawChevron(img, nx, ny, cx, cy, cellSize, color.RGBA{220, 60, 30, 255}) // Red
+			}
+		}
+	}
+}
+
+// keyColors tells the keys and doors apart; key d and door d share
+// keyColors[d-1].
+var keyColors = [MaxKeys]color.RGBA{
+	{230, 160, 0, 255},   // Amber
+	{200, 40, 160, 255},  // Magenta
+	{0, 160, 170, 255},   // Teal
+	{120, 70, 200, 255},  // Purple
+	{210, 90, 30, 255},   // Rust
+	{90, 150, 30, 255},   // Olive
+	{40, 80, 160, 255},   // Navy
+	{150, 110, 70, 255},  // Brown
+}
+
+// drawKeysAndDoors paints each door as a solid block and each key as a
+// small disc in the door's colour.
+func (m *Maze) drawKeysAndDoors(img *image.RGBA, cellSize int) {
+	for r := range m.Grid {
+		for c, cell := range m.Grid[r] {
+			if cell.Masked || (cell.Key == 0 && cell.Door == 0) {
+				continue
+			}
+			cx, cy := m.cellCentre(r, c, cellSize)
+			if cell.Door > 0 {
+				s := max(cellSize*3/10, 1)
+				x, y := int(math.Round(cx)), int(math.Round(cy))
+				draw.Draw(img, image.Rect(x-s, y-s, x+s+1, y+s+1), &image.Uniform{keyColors[cell.Door-1]}, image.Point{}, draw.Src)
+			}
+			if cell.Key > 0 {
+				fillDisc(img, cx, cy, max(float64(cellSize)/5, 1), keyColors[cell.Key-1])
+			}
+		}
+	}
+}
+
+// portalColors tells the portal pairs apart; both ends of pair i are
+// drawn in portalColors[i].
+var portalColors = [MaxPortals]color.RGBA{
+	{0, 170, 255, 255},   // Sky
+	{255, 80, 170, 255},  // Pink
+	{110, 210, 0, 255},   // Lime
+	{150, 90, 255, 255},  // Violet
+	{255, 140, 0, 255},   // Orange
+	{0, 200, 170, 255},   // Aqua
+	{220, 20, 60, 255},   // Crimson
+	{200, 180, 0, 255},   // Gold
+}
+
+// drawPortals rings both ends of each portal pair in the pair's colour.
+// On a single floor of a multi-level maze, an end whose partner is on
+// another floor also gets a dot in the middle of its ring.
+func (m *Maze) drawPortals(img *image.RGBA, cellSize int) {
+	for i, portal := range m.Portals {
+		for j, p := range portal {
+			if !m.inBounds(p[0], p[1]) {
+				continue
+			}
+			cx, cy := m.cellCentre(p[0], p[1], cellSize)
+			outer := max(float64(cellSize)*0.35, 2)
+			col := portalColors[i%MaxPortals]
+			strokeRing(img, cx, cy, outer, max(outer/3, 1), col)
+			if other := portal[1-j]; !m.inBounds(other[0], other[1]) {
+				fillDisc(img, cx, cy, max(outer/4, 1), col)
+			}
+		}
+	}
+}
+
+// strokeRing paints a circle outline of the given outer radius and width.
+func strokeRing(img *image.RGBA, cx, cy, radius, width float64, col color.RGBA) {
+	for y := int(cy - radius); y <= int(cy+radius)+1; y++ {
+		for x := int(cx - radius); x <= int(cx+radius)+1; x++ {
+			if d := math.Hypot(float64(x)-cx, float64(y)-cy); d <= radius && d > radius-width {
+				img.Set(x, y, col)
+			}
+		}
+	}
+}
+
+// fillDisc paints a filled circle of the given radius.
+func fillDisc(img *image.RGBA, cx, cy, radius float64, col color.RGBA) {
+	for y := int(cy - radius); y <= int(cy+radius)+1; y++ {
+		for x := int(cx - radius); x <= int(cx+radius)+1; x++ {
+			if math.Hypot(float64(x)-cx, float64(y)-cy) <= radius {
+				img.Set(x, y, col)
+			}
+		}
+	}
+}
+
+// drawWrapMarkers points a chevron out of every open passage that leaves
+// one edge of a wrapped maze and comes back in on the other.
+func (m *Maze) drawWrapMarkers(img *image.RGBA, cellSize int) {
+	for r := range m.Grid {
+		for c, cell := range m.Grid[r] {
+			if cell.Masked {
+				continue
+			}
+			for side, isWall := range cell.Walls {
+				nr, nc, crosses := m.wraps(r, c, side)
+				if isWall || !crosses {
+					continue
+				}
+				cx, cy := m.cellCentre(r, c, cellSize)
+				nx, ny := m.cellCentre(nr, nc, cellSize)
+				drawChevron(img, cx, cy, nx, ny, cellSize, color.RGBA{0, 150, 80, 255}) // Green
+			}
+		}
+	}
+}
+
+// drawLevels paints each floor on its own canvas, marks the stairs leading
+// up and down from it, and copies it into place left to right.
+func (m *Maze) drawLevels(img *image.RGBA, cellSize int) {
+	width, height := m.floorSize(cellSize)
+	up := m.floorTopo().Sides()
+	for l := range m.Levels {
+		f := m.floor(l)
+		canvas := f.prepareCanvas(cellSize)
+		f.drawMaze(canvas, cellSize)
+
+		for r := range f.Grid {
+			cell := m.Grid[l*m.Rows+r]
+			for c := range cell {
+				if cell[c].Masked {
+					continue
+				}
+				cx, cy := f.cellCentre(r, c, cellSize)
+				if !cell[c].Walls[up] {
+					drawStair(canvas, cx, cy, cellSize, -1)
+				}
+				if !cell[c].Walls[up+1] {
+					drawStair(canvas, cx, cy, cellSize, 1)
+				}
+			}
+		}
+
+		offset := image.Pt(l*(width+cellSize), 0)
+		draw.Draw(img, image.Rect(0, 0, width, height).Add(offset), canvas, image.Point{}, draw.Src)
+	}
+}
+
+// cellCentre returns the pixel centre of cell (r, c) on a single floor.
+func (m *Maze) cellCentre(r, c, cellSize int) (float64, float64) {
+	switch m.shape().(type) {
+	case hexTopology:
+		return newHexGeometry(cellSize).center(r, c)
+	case polarTopology:
+		angle := 2 * math.Pi * (float64(c) + 0.5) / float64(len(m.Grid[r]))
+		return m.polarPoint((float64(r)+0.5)*float64(cellSize), angle, cellSize)
+	}
+	return (float64(c) + 0.5) * float64(cellSize), (float64(r) + 0.5) * float64(cellSize)
+}
+
+// drawStair marks a staircase with a chevron pointing up (dir -1) or down
+// (dir 1); a cell with both reads as a diamond.
+func drawStair(img *image.RGBA, cx, cy float64, cellSize, dir int) {
+	col := color.RGBA{30, 100, 220, 255} // Blue
+	s := max(cellSize/4, 1)
+	x, y := int(math.Round(cx)), int(math.Round(cy))
+	drawLine(img, x-s, y, x, y+dir*s, col)
+	drawLine(img, x, y+dir*s, x+s, y, col)
+}
+
+// drawChevron marks a passage with a chevron on the edge between two cell
+// centres, pointing from the first towards the second.
+func drawChevron(img *image.RGBA, cx, cy, nx, ny float64, cellSize int, col color.RGBA) {
+	dx, dy := nx-cx, ny-cy
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	dx, dy = dx/length, dy/length
+	s := float64(max(cellSize/4, 1))
+	tipX, tipY := (cx+nx)/2, (cy+ny)/2
+	baseX, baseY := tipX-dx*s, tipY-dy*s
+	x, y := int(math.Round(tipX)), int(math.Round(tipY))
+	for _, sign := range []float64{-1, 1} {
+		drawLine(img, int(math.Round(baseX-dy*s*sign)), int(math.Round(baseY+dx*s*sign)), x, y, col)
+	}
+}
+
+// cellFill returns the colour to fill cell (r, c) with: green for the
+// start, red for the end, and a tint for costly terrain elsewhere.
+func (m *Maze) cellFill(r, c int) (color.RGBA, bool) {
+	switch {
+	case r == m.Start[0] && c == m.Start[1]:
+		return color.RGBA{144, 238, 144, 255}, true // Light Green
+	case r == m.End[0] && c == m.End[1]:
+		return color.RGBA{255, 99, 71, 255}, true // Red
+	}
+	return terrainColor(m.Grid[r][c].Cost)
+}
+
+// drawSquareCell paints one square cell and its walls.
+func (m *Maze) drawSquareCell(img *image.RGBA, r, c, cellSize int) {
+	x := c * cellSize
+	y := r * cellSize
+	cell := m.Grid[r][c]
+
+	if col, ok := m.cellFill(r, c); ok {
+		m.fillCell(img, x, y, cellSize, col)
+	}
+
+	// TOP WALL
+	if cell.Walls[0] {
+		m.paintWall(img, x, y, cellSize, 0, cell.WallWeights[0])
+	}
+	// RIGHT WALL
+	if cell.Walls[1] {
+		m.paintWall(img, x, y, cellSize, 1, cell.WallWeights[1])
+	}
+	// BOTTOM WALL
+	if cell.Walls[2] {
+		m.paintWall(img, x, y, cellSize, 2, cell.WallWeights[2])
+	}
+	// LEFT WALL
+	if cell.Walls[3] {
+		m.paintWall(img, x, y, cellSize, 3, cell.WallWeights[3])
+	}
+}
+
+// drawWeaveCell paints a square cell as a corridor inset from the cell's
+// edges, so a passage running under a crossing stays visible in the gaps
+// either side of the bridge over it.
+func (m *Maze) drawWeaveCell(img *image.RGBA, r, c, cellSize int) {
+	inset := max(cellSize/5, 1)
+	x1, y1 := c*cellSize, r*cellSize
+	x2, y2 := x1+inset, y1+inset
+	x4, y4 := x1+cellSize, y1+cellSize
+	x3, y3 := x4-inset, y4-inset
+	cell := m.Grid[r][c]
+
+	if col, ok := m.cellFill(r, c); ok {
+		m.fillCell(img, x2, y2, x3-x2, col)
+	}
+
+	// open sides run the corridor out to the cell edge, closed ones cap it
+	line := func(side, xa, ya, xb, yb int) {
+		drawLine(img, xa, ya, xb, yb, m.getWallColor(cell.WallWeights[side]))
+	}
+	northSouth := func(side, ya, yb int) {
+		line(side, x2, ya, x2, yb)
+		line(side, x3, ya, x3, yb)
+	}
+	eastWest := func(side, xa, xb int) {
+		line(side, xa, y2, xb, y2)
+		line(side, xa, y3, xb, y3)
+	}
+
+	// TOP
+	if !cell.Walls[0] {
+		northSouth(0, y1, y2)
+	} else {
+		line(0, x2, y2, x3, y2)
+	}
+	// RIGHT
+	if !cell.Walls[1] {
+		eastWest(1, x3, x4)
+	} else {
+		line(1, x3, y2, x3, y3)
+	}
+	// BOTTOM
+	if !cell.Walls[2] {
+		northSouth(2, y3, y4)
+	} else {
+		line(2, x2, y3, x3, y3)
+	}
+	// LEFT
+	if !cell.Walls[3] {
+		eastWest(3, x1, x2)
+	} else {
+		line(3, x2, y2, x2, y3)
+	}
+
+	// the tunnel's walls show only beside the bridge, stopping at its sides
+	if cell.Tunnel {
+		if !cell.Walls[0] {
+			eastWest(1, x3, x4)
+			eastWest(3, x1, x2)
+		} else {
+			northSouth(0, y1, y2)
+			northSouth(2, y3, y4)
+		}
+	}
+}
+
+// hexGeometry sizes pointy-topped hexagons so that cellSize is the
+// distance between opposite flat sides.
+type hexGeometry struct {
+	width  float64 // flat side to flat side
+	radius float64 // centre to corner
+}
+
+func newHexGeometry(cellSize int) hexGeometry {
+	return hexGeometry{width: float64(cellSize), radius: float64(cellSize) / math.Sqrt(3)}
+}
+
+// center returns the pixel centre of hex cell (r, c).
+func (h hexGeometry) center(r, c int) (float64, float64) {
+	x := h.width * (float64(c) + 0.5 + 0.5*float64(r&1))
+	y := h.radius * (1 + 1.5*float64(r))
+	return x, y
+}
+
+// corner returns corner i of the hex centred on (cx, cy), counting
+// clockwise from the top, so side i runs from corner i to corner i+1.
+func (h hexGeometry) corner(cx, cy float64, i int) (int, int) {
+	angle := math.Pi / 180 * float64(60*i-90)
+	return int(math.Round(cx + h.radius*math.Cos(angle))), int(math.Round(cy + h.radius*math.Sin(angle)))
+}
+
+// drawHexCell paints one hexagonal cell and its walls.
+func (m *Maze) drawHexCell(img *image.RGBA, r, c int, h hexGeometry) {
+	cx, cy := h.center(r, c)
+	cell := m.Grid[r][c]
+
+	if col, ok := m.cellFill(r, c); ok {
+		fillHex(img, cx, cy, h, col)
+	}
+
+	for side, isWall := range cell.Walls {
+		if !isWall {
+			continue
+		}
+		x0, y0 := h.corner(cx, cy, side)
+		x1, y1 := h.corner(cx, cy, (side+1)%6)
+		drawLine(img, x0, y0, x1, y1, m.getWallColor(cell.WallWeights[side]))
+	}
+}
+
+// fillHex paints the interior of a hexagon row by row; the half-width
+// narrows linearly over the slanted top and bottom quarters.
+func fillHex(img *image.RGBA, cx, cy float64, h hexGeometry, col color.RGBA) {
+	for y := int(math.Ceil(cy-h.radius)) + 1; y < int(cy+h.radius); y++ {
+		dy := math.Abs(float64(y) - cy)
+		half := h.width / 2
+		if dy > h.radius/2 {
+			half *= (h.radius - dy) / (h.radius / 2)
+		}
+		for x := int(math.Ceil(cx-half)) + 1; x < int(cx+half); x++ {
+			img.Set(x, y, col)
+		}
+	}
+}
+
+// polarPoint converts a radius and a clockwise angle from north into
+// pixels, with the maze centred in a canvas of Rows rings on each side.
+func (m *Maze) polarPoint(radius, angle float64, cellSize int) (float64, float64) {
+	centre := float64(m.Rows * cellSize)
+	return centre + radius*math.Sin(angle), centre - radius*math.Cos(angle)
+}
+
+// polarArc samples an arc finely enough that consecutive points are at
+// most two pixels apart.
+func (m *Maze) polarArc(radius, from, to float64, cellSize int) [][2]float64 {
+	steps := int(math.Ceil(radius*(to-from)/2)) + 1
+	points := make([][2]float64, steps+1)
+	for i := range points {
+		x, y := m.polarPoint(radius, from+(to-from)*float64(i)/float64(steps), cellSize)
+		points[i] = [2]float64{x, y}
+	}
+	return points
+}
+
+// drawPolarCell paints one ring segment: arcs for the inward and outward
+// walls and radial lines for the clockwise and counter-clockwise ones.
+func (m *Maze) drawPolarCell(img *image.RGBA, r, c, cellSize int) {
+	cell := m.Grid[r][c]
+	n := float64(len(m.Grid[r]))
+	inner, outer := float64(r*cellSize), float64((r+1)*cellSize)
+	theta0, theta1 := 2*math.Pi*float64(c)/n, 2*math.Pi*float64(c+1)/n
+	thetaMid := (theta0 + theta1) / 2
+
+	if col, ok := m.cellFill(r, c); ok {
+		m.fillPolar(img, inner, outer, theta0, theta1, cellSize, col)
+	}
+
+	polyline := func(points [][2]float64, weight int) {
+		col := m.getWallColor(weight)
+		for i := 1; i < len(points); i++ {
+			a, b := points[i-1], points[i]
+			drawLine(img, int(math.Round(a[0])), int(math.Round(a[1])), int(math.Round(b[0])), int(math.Round(b[1])), col)
+		}
+	}
+	radial := func(angle float64) [][2]float64 {
+		x0, y0 := m.polarPoint(inner, angle, cellSize)
+		x1, y1 := m.polarPoint(outer, angle, cellSize)
+		return [][2]float64{{x0, y0}, {x1, y1}}
+	}
+
+	split := m.hasSide(r, c, 3)
+	for side, isWall := range cell.Walls {
+		if !isWall || !m.hasSide(r, c, side) {
+			continue
+		}
+		var points [][2]float64
+		switch side {
+		case 0: // INWARD
+			points = m.polarArc(inner, theta0, theta1, cellSize)
+		case 1: // CLOCKWISE
+			points = radial(theta1)
+		case 2: // OUTWARD
+			if split {
+				points = m.polarArc(outer, theta0, thetaMid, cellSize)
+			} else {
+				points = m.polarArc(outer, theta0, theta1, cellSize)
+			}
+		case 3: // SECOND OUTWARD
+			points = m.polarArc(outer, thetaMid, theta1, cellSize)
+		case 4: // COUNTER-CLOCKWISE
+			points = radial(theta0)
+		}
+		polyline(points, cell.WallWeights[side])
+	}
+}
+
+// fillPolar paints the interior of a ring segment, testing each pixel in
+// the segment's bounding box.
+func (m *Maze) fillPolar(img *image.RGBA, inner, outer, theta0, theta1 float64, cellSize int, col color.RGBA) {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, radius := range []float64{inner, outer} {
+		for _, p := range m.polarArc(radius, theta0, theta1, cellSize) {
+			minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+			minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+		}
+	}
+
+	centre := float64(m.Rows * cellSize)
+	for y := int(minY); y <= int(maxY); y++ {
+		for x := int(minX); x <= int(maxX); x++ {
+			dx, dy := float64(x)-centre, centre-float64(y)
+			radius := math.Hypot(dx, dy)
+			angle := math.Atan2(dx, dy)
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			if radius > inner+1 && radius < outer-1 && angle > theta0 && angle < theta1 {
+				img.Set(x, y, col)
+			}
+		}
+	}
+}
+
+// drawLine paints a one pixel line with Bresenham's algorithm.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, col color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	for err := dx + dy; ; {
+		img.Set(x0, y0, col)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
 }
 
 // fillCell paints the interior of a maze square.
@@ -2,7 +2,6 @@ package maze
 
 import (
 	"container/heap"
-	"math"
 )
 
 type Point [2]int
@@ -38,8 +37,30 @@ func (pq *PriorityQueue) Pop() interface{} {
 	return item
 }
 
-// SolveAStar uses Manhattan Heuristic
+// SolveAStar uses the grid distance heuristic (Manhattan on square grids),
+// adjusted by heuristic for tunnels and portals. It minimises the terrain
+// cost of the path, which is its length on mazes without terrain.
 func (m *Maze) SolveAStar() ([][2]int, [][2]int) {
+	if m.HasDoors() {
+		return m.solveLocked(m.cost, func(moves, estimate int) int { return moves + estimate })
+	}
+	return m.solveCheapest(m.heuristic())
+}
+
+// SolveDijkstra expands cells in order of the terrain cost to reach them,
+// finding a cheapest path without any estimate of the cost left.
+func (m *Maze) SolveDijkstra() ([][2]int, [][2]int) {
+	if m.HasDoors() {
+		return m.solveLocked(m.cost, func(moves, _ int) int { return moves })
+	}
+	return m.solveCheapest(func(Point, Point) int { return 0 })
+}
+
+// solveCheapest finds a path of least terrain cost, ordering the frontier
+// by the cost so far plus heuristic's estimate of the cost left, which
+// must never overshoot. Every move costs at least one, so step counts
+// qualify.
+func (m *Maze) solveCheapest(heuristic func(p1, p2 Point) int) ([][2]int, [][2]int) {
 	start, end := Point{m.Start[0], m.Start[1]}, Point{m.End[0], m.End[1]}
 	visited, cameFrom, gScore := [][2]int{}, make(map[Point]Point), make(map[Point]int)
 	gScore[start] = 0
@@ -49,17 +70,21 @@ func (m *Maze) SolveAStar() ([][2]int, [][2]int) {
 	heap.Push(pq, &Item{point: start, priority: 0})
 
 	for pq.Len() > 0 {
-		curr := heap.Pop(pq).(*Item).point
+		item := heap.Pop(pq).(*Item)
+		curr := item.point
+		if item.priority > gScore[curr]+heuristic(curr, end) {
+			continue // superseded by a cheaper route to the same cell
+		}
 		visited = append(visited, [2]int{curr[0], curr[1]})
 
 		if curr == end { return visited, m.reconstructPath(cameFrom, curr) }
 
 		for _, next := range m.GetNeighbors(curr) {
-			tentativeG := gScore[curr] + 1
+			tentativeG := gScore[curr] + m.cost(next)
 			if val, ok := gScore[next]; !ok || tentativeG < val {
 				cameFrom[next] = curr
 				gScore[next] = tentativeG
-				fScore := tentativeG + m.manhattan(next, end)
+				fScore := tentativeG + heuristic(next, end)
 				heap.Push(pq, &Item{point: next, priority: fScore})
 			}
 		}
@@ -67,8 +92,12 @@ func (m *Maze) SolveAStar() ([][2]int, [][2]int) {
 	return visited, nil
 }
 
-// SolveBFS for shortest path in unweighted grid
+// SolveBFS for shortest path in unweighted grid, counting moves and
+// ignoring terrain
 func (m *Maze) SolveBFS() ([][2]int, [][2]int) {
+	if m.HasDoors() {
+		return m.solveLocked(unitCost, func(moves, _ int) int { return moves })
+	}
 	start, end := Point{m.Start[0], m.Start[1]}, Point{m.End[0], m.End[1]}
 	visited, queue, cameFrom := [][2]int{}, []Point{start}, make(map[Point]Point)
 	seen := map[Point]bool{start: true}
@@ -90,6 +119,9 @@ func (m *Maze) SolveBFS() ([][2]int, [][2]int) {
 }
 
 func (m *Maze) SolveGreedy() ([][2]int, [][2]int) {
+	if m.HasDoors() {
+		return m.solveLocked(unitCost, func(_, estimate int) int { return estimate })
+	}
 	start, end := Point{m.Start[0], m.Start[1]}, Point{m.End[0], m.End[1]}
 	visited, cameFrom := [][2]int{}, make(map[Point]Point)
 	seen := map[Point]bool{start: true}
@@ -97,7 +129,7 @@ func (m *Maze) SolveGreedy() ([][2]int, [][2]int) {
 	pq := &PriorityQueue{}
 	heap.Init(pq)
 	// Initial priority is just the distance to the end
-	heap.Push(pq, &Item{point: start, priority: m.manhattan(start, end)})
+	heap.Push(pq, &Item{point: start, priority: m.distance(start, end)})
 
 	for pq.Len() > 0 {
 		curr := heap.Pop(pq).(*Item).point
@@ -111,7 +143,7 @@ func (m *Maze) SolveGreedy() ([][2]int, [][2]int) {
 			if !seen[next] {
 				seen[next] = true
 				cameFrom[next] = curr
-				priority := m.manhattan(next, end)
+				priority := m.distance(next, end)
 				heap.Push(pq, &Item{point: next, priority: priority})
 			}
 		}
@@ -119,8 +151,39 @@ func (m *Maze) SolveGreedy() ([][2]int, [][2]int) {
 	return visited, [][2]int{}
 }
 
-func (m *Maze) manhattan(p1, p2 Point) int {
-	return int(math.Abs(float64(p1[0]-p2[0])) + math.Abs(float64(p1[1]-p2[1])))
+// distance is the A* heuristic: the fewest moves between two cells on an
+// open grid of the maze's topology.
+func (m *Maze) distance(p1, p2 Point) int {
+	return m.topo().Distance(p1, p2)
+}
+
+// heuristic returns an A* estimate that never overshoots the moves left.
+// A tunnel covers two cells in one move, so the grid distance is halved on
+// weave mazes. With portals the estimate is also capped by walking to the
+// nearest portal, stepping through and walking on from the portal nearest
+// the target.
+func (m *Maze) heuristic() func(p1, p2 Point) int {
+	walk := m.distance
+	if m.HasTunnels() {
+		walk = func(p1, p2 Point) int { return m.distance(p1, p2) / 2 }
+	}
+	if len(m.Portals) == 0 {
+		return walk
+	}
+	nearest := func(p Point) int {
+		best := -1
+		for _, portal := range m.Portals {
+			for _, q := range portal {
+				if d := walk(p, q); best < 0 || d < best {
+					best = d
+				}
+			}
+		}
+		return best
+	}
+	return func(p1, p2 Point) int {
+		return min(walk(p1, p2), nearest(p1)+1+nearest(p2))
+	}
 }
 
 func (m *Maze) reconstructPath(cameFrom map[Point]Point, current Point) [][2]int {
@@ -130,4 +193,33 @@ func (m *Maze) reconstructPath(cameFrom map[Point]Point, current Point) [][2]int
 		if p, ok := cameFrom[current]; ok { current = p } else { break }
 	}
 	return path
-}
\ No newline at end of file
+}
+
+// Solver describes a path-finding algorithm available to the solve endpoint.
+type Solver struct {
+	Name        string                                 `json:"name"`
+	Description string                                 `json:"description"`
+	Solve       func(m *Maze) (visited, path [][2]int) `json:"-"`
+}
+
+var solvers = []Solver{
+	{Name: "astar", Description: "A* search guided by grid distance; finds a cheapest path, counting terrain costs.", Solve: (*Maze).SolveAStar},
+	{Name: "dijkstra", Description: "Dijkstra's algorithm; finds a cheapest path, counting terrain costs, by flooding outward in order of cost.", Solve: (*Maze).SolveDijkstra},
+	{Name: "bfs", Description: "Breadth-first search; finds a path with the fewest moves by flooding outward, ignoring terrain.", Solve: (*Maze).SolveBFS},
+	{Name: "greedy", Description: "Greedy best-first search; fast but not always shortest.", Solve: (*Maze).SolveGreedy},
+}
+
+// Solvers lists every available solver.
+func Solvers() []Solver {
+	return solvers
+}
+
+// LookupSolver finds a solver by name.
+func LookupSolver(name string) (Solver, bool) {
+	for _, s := range solvers {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Solver{}, false
+}
@@ -0,0 +1,164 @@
+package maze
+
+import (
+	"bufio"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// WriteText streams the maze as ASCII art in the same style as Print,
+// emitting each row as soon as it has been generated. It stops at the
+// first failed write, so a dropped client does not keep it generating.
+func (e *EllerStream) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var last []Cell
+
+	for row, ok := e.Next(); ok; row, ok = e.Next() {
+		for _, cell := range row {
+			if cell.Walls[0] {
+				bw.WriteString("+---")
+			} else {
+				bw.WriteString("+   ")
+			}
+		}
+		bw.WriteString("+\n")
+
+		for _, cell := range row {
+			if cell.Walls[3] {
+				bw.WriteString("|   ")
+			} else {
+				bw.WriteString("    ")
+			}
+		}
+		edge := " \n"
+		if row[len(row)-1].Walls[1] {
+			edge = "|\n"
+		}
+		// bufio keeps the first error, so checking once a row is enough
+		if _, err := bw.WriteString(edge); err != nil {
+			return err
+		}
+		last = row
+	}
+
+	// closing bottom edge for grid
+	for _, cell := range last {
+		if cell.Walls[2] {
+			bw.WriteString("+---")
+		} else {
+			bw.WriteString("+   ")
+		}
+	}
+	if last != nil {
+		bw.WriteString("+\n")
+	}
+	return bw.Flush()
+}
+
+// WritePNG streams the maze as a grayscale PNG. image/png needs the whole
+// image in memory, so the chunks are written by hand and each maze row is
+// rasterised into scanlines and compressed as it is generated.
+func (e *EllerStream) WritePNG(w io.Writer, cellSize int) error {
+	width := e.Cols*cellSize + 1
+	height := e.Rows*cellSize + 1
+	if cellSize < 1 || int64(e.Cols)*int64(cellSize)+1 > math.MaxInt32 || int64(e.Rows)*int64(cellSize)+1 > math.MaxInt32 {
+		return fmt.Errorf("maze of %dx%d cells is too large for a PNG", e.Rows, e.Cols)
+	}
+
+	if _, err := io.WriteString(w, "\x89PNG\r\n\x1a\n"); err != nil {
+		return err
+	}
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(height))
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 0 // grayscale
+	if err := writePNGChunk(w, "IHDR", ihdr); err != nil {
+		return err
+	}
+
+	// every flush of the buffer becomes one IDAT chunk
+	idat := bufio.NewWriterSize(&idatWriter{w: w}, 1<<16)
+	zw := zlib.NewWriter(idat)
+
+	// scanline[0] is the PNG filter type (none)
+	scanline := make([]byte, width+1)
+	writeLine := func(cells []Cell, y int) error {
+		for x := 1; x < len(scanline); x++ {
+			scanline[x] = 255
+		}
+		for c, cell := range cells {
+			x := 1 + c*cellSize
+			if y == 0 && cell.Walls[0] || y == cellSize && cell.Walls[2] {
+				for i := 0; i <= cellSize; i++ {
+					scanline[x+i] = 0
+				}
+			}
+			if cell.Walls[3] {
+				scanline[x] = 0
+			}
+			if c == len(cells)-1 && cell.Walls[1] {
+				scanline[x+cellSize] = 0
+			}
+		}
+		_, err := zw.Write(scanline)
+		return err
+	}
+
+	var last []Cell
+	for row, ok := e.Next(); ok; row, ok = e.Next() {
+		for y := 0; y < cellSize; y++ {
+			if err := writeLine(row, y); err != nil {
+				return err
+			}
+		}
+		last = row
+	}
+	if last != nil {
+		if err := writeLine(last, cellSize); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := idat.Flush(); err != nil {
+		return err
+	}
+	return writePNGChunk(w, "IEND", nil)
+}
+
+// idatWriter wraps every write in its own IDAT chunk.
+type idatWriter struct {
+	w io.Writer
+}
+
+func (iw *idatWriter) Write(p []byte) (int, error) {
+	if err := writePNGChunk(iw.w, "IDAT", p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func writePNGChunk(w io.Writer, name string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], name)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	footer := binary.BigEndian.AppendUint32(nil, crc.Sum32())
+
+	for _, b := range [][]byte{header, data, footer} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
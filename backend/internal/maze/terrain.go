@@ -0,0 +1,158 @@
+package maze
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math/rand/v2"
+)
+
+// Terrain gives cells a cost to enter, so the cheapest route through a
+// maze need not be the shortest. A cell's Cost is what stepping onto it
+// adds to a path; zero counts as CostRoad.
+
+// Costs of the terrain types.
+const (
+	CostRoad  = 1
+	CostMud   = 3
+	CostWater = 5
+	MaxCost   = CostWater
+)
+
+// Terrain sources choose how cell costs are derived at generation time.
+const (
+	TerrainNone  = ""
+	TerrainNoise = "noise" // smooth random patches of mud and water
+	TerrainImage = "image" // darker pixels of an uploaded image cost more
+)
+
+// ErrUnknownTerrain is returned for a terrain source that does not exist.
+var ErrUnknownTerrain = errors.New("unknown terrain")
+
+// ValidateTerrain checks that source names a terrain source.
+func ValidateTerrain(source string) error {
+	switch source {
+	case TerrainNone, TerrainNoise, TerrainImage:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownTerrain, source)
+}
+
+// cost is what entering p adds to a path.
+func (m *Maze) cost(p Point) int {
+	return max(m.Grid[p[0]][p[1]].Cost, CostRoad)
+}
+
+// unitCost counts every move as one, for solvers that ignore terrain.
+func unitCost(Point) int { return 1 }
+
+// HasTerrain reports whether any cell costs more than a road to enter.
+func (m *Maze) HasTerrain() bool {
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if m.Grid[r][c].Cost > CostRoad {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PathCost returns the total cost of walking path, not counting its first
+// cell, where the walker starts.
+func (m *Maze) PathCost(path [][2]int) int {
+	total := 0
+	for i := 1; i < len(path); i++ {
+		total += m.cost(Point(path[i]))
+	}
+	return total
+}
+
+// SetTerrain gives every cell the cost in costs, which must match the grid
+// dimensions with one row per grid row on every level.
+func (m *Maze) SetTerrain(costs [][]int) error {
+	if len(costs) != len(m.Grid) {
+		return fmt.Errorf("terrain has %d rows, maze has %d", len(costs), len(m.Grid))
+	}
+	for r := range costs {
+		if len(costs[r]) != len(m.Grid[r]) {
+			return fmt.Errorf("terrain row %d has %d columns, maze has %d", r, len(costs[r]), len(m.Grid[r]))
+		}
+		for c, cost := range costs[r] {
+			if cost < 0 || cost > MaxCost {
+				return fmt.Errorf("terrain cost %d at (%d, %d) is outside 0 to %d", cost, r, c, MaxCost)
+			}
+		}
+	}
+	for r := range costs {
+		for c, cost := range costs[r] {
+			m.Grid[r][c].Cost = cost
+		}
+	}
+	return nil
+}
+
+// AddNoiseTerrain covers the maze in smooth random patches of road, mud
+// and water.
+func (m *Maze) AddNoiseTerrain() {
+	m.addNoiseTerrain(m.Rand())
+}
+
+// noiseSpacing is the distance in cells between the random values that
+// noise terrain blends, setting the size of its patches.
+const noiseSpacing = 4.0
+
+func (m *Maze) addNoiseTerrain(rng *rand.Rand) {
+	// sample the noise at cell centres on each floor, so patches keep their
+	// size on every topology, with separate values for every floor
+	width, height := 0.0, 0.0
+	for r := range m.Rows {
+		for c := range m.Grid[r] {
+			x, y := m.cellCentre(r, c, 1)
+			width, height = max(width, x), max(height, y)
+		}
+	}
+	cols, rows := int(width/noiseSpacing)+2, int(height/noiseSpacing)+2
+	lattice := make([]float64, max(m.Levels, 1)*rows*cols)
+	for i := range lattice {
+		lattice[i] = rng.Float64()
+	}
+
+	smooth := func(t float64) float64 { return t * t * (3 - 2*t) }
+	for r := range m.Grid {
+		level, row := r/m.Rows, r%m.Rows
+		for c := range m.Grid[r] {
+			x, y := m.cellCentre(row, c, 1)
+			x, y = x/noiseSpacing, y/noiseSpacing
+			i, j := int(y), int(x)
+			ty, tx := smooth(y-float64(i)), smooth(x-float64(j))
+			at := func(di, dj int) float64 { return lattice[(level*rows+i+di)*cols+j+dj] }
+			top := at(0, 0) + (at(0, 1)-at(0, 0))*tx
+			bottom := at(1, 0) + (at(1, 1)-at(1, 0))*tx
+			m.Grid[r][c].Cost = terrainCost(top + (bottom-top)*ty)
+		}
+	}
+}
+
+// terrainCost classes a brightness from 0 (dark) to 1 (light): the light
+// half is road, then mud, with the darkest fifth water.
+func terrainCost(brightness float64) int {
+	switch {
+	case brightness >= 0.5:
+		return CostRoad
+	case brightness >= 0.3:
+		return CostMud
+	}
+	return CostWater
+}
+
+// terrainColor is the tint of a cell costing cost to enter.
+func terrainColor(cost int) (color.RGBA, bool) {
+	switch {
+	case cost >= CostWater:
+		return color.RGBA{160, 200, 240, 255}, true // Light Blue
+	case cost > CostRoad:
+		return color.RGBA{210, 180, 140, 255}, true // Tan
+	}
+	return color.RGBA{}, false
+}
@@ -0,0 +1,70 @@
+package maze
+
+import "testing"
+
+func TestCheapestPathCost(t *testing.T) {
+	tests := []struct {
+		name    string
+		portals int
+		oneWay  float64
+		keys    int
+	}{
+		{"terrain", 0, 0, 0},
+		{"terrain with portals", 4, 0, 0},
+		{"terrain with one-way passages", 0, 0.2, 0},
+		{"terrain with keys", 0, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := range int64(10) {
+				m := braidedMaze(t, 20, 20, seed, 0.5)
+				m.AddNoiseTerrain()
+				m.PlacePortals(tt.portals)
+				m.AddOneWays(tt.oneWay)
+				m.PlaceKeys(tt.keys)
+
+				_, astar := m.SolveAStar()
+				_, dijkstra := m.SolveDijkstra()
+				_, bfs := m.SolveBFS()
+				if len(dijkstra) == 0 {
+					t.Fatalf("seed %d: no solution", seed)
+				}
+				if a, d := m.PathCost(astar), m.PathCost(dijkstra); a != d {
+					t.Errorf("seed %d: A* path costs %d, Dijkstra's %d", seed, a, d)
+				}
+				if b, d := m.PathCost(bfs), m.PathCost(dijkstra); b < d {
+					t.Errorf("seed %d: BFS path costs %d, below Dijkstra's %d", seed, b, d)
+				}
+			}
+		})
+	}
+}
+
+func TestTerrainRoundTrip(t *testing.T) {
+	m := braidedMaze(t, 20, 20, 7, 0.5)
+	m.AddNoiseTerrain()
+	if !m.HasTerrain() {
+		t.Fatal("no terrain added")
+	}
+
+	restored := NewMaze(m.Rows, m.Cols)
+	if err := restored.DecodeFeatures(m.EncodeFeatures()); err != nil {
+		t.Fatal(err)
+	}
+	// roads are not stored, so compare what each cell costs to enter
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if p := (Point{r, c}); restored.cost(p) != m.cost(p) {
+				t.Errorf("cell (%d, %d) restored costing %d, want %d", r, c, restored.cost(p), m.cost(p))
+			}
+		}
+	}
+}
+
+func TestDecodeFeaturesRejectsInvalidCost(t *testing.T) {
+	for _, features := range []string{`[{"row":0,"col":0,"cost":-1}]`, `[{"row":0,"col":0,"cost":6}]`} {
+		if err := NewMaze(4, 5).DecodeFeatures(features); err == nil {
+			t.Errorf("%s decoded without error", features)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package maze
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// ErrUnknownTopology is returned when no topology is registered under the
+// requested name.
+var ErrUnknownTopology = errors.New("unknown topology")
+
+// Topology describes how the cells of a grid fit together. Every cell has
+// Sides() walls, and wall i faces the cell returned by Neighbor for side i.
+// Rows may hold different numbers of cells.
+type Topology interface {
+	Name() string
+	Sides() int
+	// SideNames labels each side; the labels appear in weight keys.
+	SideNames() []string
+	// RowLengths returns the number of cells in each row of a grid
+	// requested as rows x cols.
+	RowLengths(rows, cols int) []int
+	// Neighbor returns the cell across side of (r, c), which may lie
+	// outside the grid.
+	Neighbor(m *Maze, r, c, side int) (int, int)
+	// Distance is a lower bound on the moves between two cells, used as
+	// the A* heuristic.
+	Distance(a, b Point) int
+}
+
+// partialSides is implemented by topologies where some cells lack some
+// sides. A missing side stays walled and never counts as a border.
+type partialSides interface {
+	HasSide(m *Maze, r, c, side int) bool
+}
+
+// startEndPlacer is implemented by topologies with a natural entrance and
+// exit, used instead of two random border cells.
+type startEndPlacer interface {
+	PlaceStartEnd(m *Maze, rng *rand.Rand) (Point, Point)
+}
+
+var topologies = map[string]Topology{}
+
+// RegisterTopology makes a topology available under its name.
+func RegisterTopology(t Topology) {
+	if _, exists := topologies[t.Name()]; exists {
+		panic("maze: topology registered twice: " + t.Name())
+	}
+	topologies[t.Name()] = t
+}
+
+// LookupTopology finds a topology by name. An empty name is the square
+// grid every maze used before topologies existed.
+func LookupTopology(name string) (Topology, error) {
+	if name == "" {
+		return squareTopology{}, nil
+	}
+	t, ok := topologies[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTopology, name)
+	}
+	return t, nil
+}
+
+func init() {
+	RegisterTopology(squareTopology{})
+	RegisterTopology(hexTopology{})
+	RegisterTopology(polarTopology{})
+}
+
+// rectRows gives every row the same number of cells.
+func rectRows(rows, cols int) []int {
+	lengths := make([]int, rows)
+	for r := range lengths {
+		lengths[r] = cols
+	}
+	return lengths
+}
+
+// squareTopology is the classic grid with walls 0:Top, 1:Right, 2:Bottom,
+// 3:Left.
+type squareTopology struct{}
+
+// gridDirs holds the square neighbour offsets, indexed by wall.
+var gridDirs = [][]int{{-1, 0}, {0, 1}, {1, 0}, {0, -1}}
+
+func (squareTopology) Name() string        { return "square" }
+func (squareTopology) Sides() int          { return 4 }
+func (squareTopology) SideNames() []string { return []string{"top", "right", "bottom", "left"} }
+
+func (squareTopology) RowLengths(rows, cols int) []int { return rectRows(rows, cols) }
+
+func (squareTopology) Neighbor(_ *Maze, r, c, side int) (int, int) {
+	d := gridDirs[side]
+	return r + d[0], c + d[1]
+}
+
+func (squareTopology) Distance(a, b Point) int {
+	return abs(a[0]-b[0]) + abs(a[1]-b[1])
+}
+
+// hexTopology lays pointy-topped hexagons out in rows, with odd rows
+// shifted half a cell to the right. Walls run clockwise from the
+// north-east: 0:NE, 1:E, 2:SE, 3:SW, 4:W, 5:NW.
+type hexTopology struct{}
+
+// hexDirs holds the neighbour offsets for even and odd rows.
+var hexDirs = [2][6][2]int{
+	{{-1, 0}, {0, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}},
+	{{-1, 1}, {0, 1}, {1, 1}, {1, 0}, {0, -1}, {-1, 0}},
+}
+
+func (hexTopology) Name() string        { return "hex" }
+func (hexTopology) Sides() int          { return 6 }
+func (hexTopology) SideNames() []string { return []string{"ne", "e", "se", "sw", "w", "nw"} }
+
+func (hexTopology) RowLengths(rows, cols int) []int { return rectRows(rows, cols) }
+
+func (hexTopology) Neighbor(_ *Maze, r, c, side int) (int, int) {
+	d := hexDirs[r&1][side]
+	return r + d[0], c + d[1]
+}
+
+// Distance converts both cells to axial coordinates, where a hex grid
+// distance is half the sum of the three cube offsets.
+func (hexTopology) Distance(a, b Point) int {
+	aq, bq := a[1]-(a[0]-a[0]&1)/2, b[1]-(b[0]-b[0]&1)/2
+	dq, dr := aq-bq, a[0]-b[0]
+	return (abs(dq) + abs(dr) + abs(dq+dr)) / 2
+}
+
+// polarTopology arranges cells in concentric rings, with row r being ring
+// r counted outward from the centre and column c running clockwise. The
+// innermost ring holds six wedges, and a ring doubles its cell count
+// whenever its cells would otherwise grow much wider than they are deep.
+// Walls are 0:Inward, 1:Clockwise, 2:Outward, 3:Second outward (only on
+// cells whose outer ring splits them in two) and 4:Counter-clockwise.
+type polarTopology struct{}
+
+func (polarTopology) Name() string { return "polar" }
+func (polarTopology) Sides() int   { return 5 }
+func (polarTopology) SideNames() []string {
+	return []string{"in", "cw", "out", "out2", "ccw"}
+}
+
+// RowLengths ignores cols; the ring sizes follow from the ring count.
+func (polarTopology) RowLengths(rows, _ int) []int {
+	lengths := make([]int, rows)
+	for r := range lengths {
+		if r == 0 {
+			lengths[r] = 6
+			continue
+		}
+		// width of a cell at the ring's mid radius, one ring being one unit deep
+		width := 2 * math.Pi * (float64(r) + 0.5) / float64(lengths[r-1])
+		lengths[r] = lengths[r-1]
+		if width >= 1.5 {
+			lengths[r] *= 2
+		}
+	}
+	return lengths
+}
+
+// outside is returned for sides that lead nowhere.
+const outside = -1
+
+func (polarTopology) Neighbor(m *Maze, r, c, side int) (int, int) {
+	n := len(m.Grid[r])
+	switch side {
+	case 0:
+		if r == 0 {
+			return outside, outside
+		}
+		return r - 1, c / (n / len(m.Grid[r-1]))
+	case 1:
+		return r, (c + 1) % n
+	case 2, 3:
+		if side == 3 && !(polarTopology{}).HasSide(m, r, c, side) {
+			return outside, outside
+		}
+		if r == m.Rows-1 {
+			return m.Rows, c
+		}
+		ratio := len(m.Grid[r+1]) / n
+		return r + 1, c*ratio + side - 2
+	default:
+		return r, (c + n - 1) % n
+	}
+}
+
+// HasSide drops the inward side of the innermost ring, which meets at the
+// centre point, and the second outward side of cells that do not split.
+func (polarTopology) HasSide(m *Maze, r, c, side int) bool {
+	switch side {
+	case 0:
+		return r > 0
+	case 3:
+		return r < m.Rows-1 && len(m.Grid[r+1]) > len(m.Grid[r])
+	default:
+		return true
+	}
+}
+
+// Distance counts rings only; a clockwise step can cover very different
+// angles depending on the ring, so rings are the only safe lower bound.
+func (polarTopology) Distance(a, b Point) int {
+	return abs(a[0] - b[0])
+}
+
+// PlaceStartEnd opens the entrance and exit on opposite sides of the rim.
+func (polarTopology) PlaceStartEnd(m *Maze, rng *rand.Rand) (Point, Point) {
+	r := m.Rows - 1
+	n := len(m.Grid[r])
+	c := rng.IntN(n)
+	return Point{r, c}, Point{r, (c + n/2) % n}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// topo returns the maze's topology, resolving it from the Topology name,
+// wrap mode and level count for mazes decoded from JSON.
+func (m *Maze) topo() Topology {
+	if m.topology == nil {
+		t, err := LookupTopology(m.Topology)
+		if err != nil {
+			t = squareTopology{}
+		}
+		if m.Wrap != WrapNone && m.Rows > 0 && m.Cols > 0 {
+			t = wrapTopology{base: t, rows: m.Rows, cols: m.Cols, vertical: m.Wrap == WrapTorus}
+		}
+		if m.Levels > 1 && m.Rows > 0 {
+			t = layeredTopology{base: t, rows: m.Rows, levels: m.Levels}
+		}
+		m.topology = t
+	}
+	return m.topology
+}
+
+// neighbor returns the cell across side of (r, c) and whether it is
+// inside the grid.
+func (m *Maze) neighbor(r, c, side int) (int, int, bool) {
+	nr, nc := m.topo().Neighbor(m, r, c, side)
+	return nr, nc, m.inBounds(nr, nc)
+}
+
+// hasSide reports whether (r, c) has the given side at all.
+func (m *Maze) hasSide(r, c, side int) bool {
+	if p, ok := m.topo().(partialSides); ok {
+		return p.HasSide(m, r, c, side)
+	}
+	return true
+}
+
+// sideTowards returns the side of (r, c) that faces the adjacent cell
+// (nr, nc), or -1 if they are not adjacent.
+func (m *Maze) sideTowards(r, c, nr, nc int) int {
+	t := m.topo()
+	for side := range t.Sides() {
+		if ar, ac := t.Neighbor(m, r, c, side); ar == nr && ac == nc {
+			return side
+		}
+	}
+	return -1
+}
+
+// rowOffsets returns the index of the first cell of every row, plus the
+// total cell count, building it from the grid on first use.
+func (m *Maze) rowOffsets() []int {
+	if len(m.offsets) != len(m.Grid)+1 {
+		offsets := make([]int, len(m.Grid)+1)
+		rect := true
+		for r, row := range m.Grid {
+			offsets[r+1] = offsets[r] + len(row)
+			rect = rect && len(row) == m.Cols
+		}
+		m.offsets, m.rectangular = offsets, rect
+	}
+	return m.offsets
+}
+
+// index numbers cells in row-major order. Rows may differ in length, so
+// generators use it instead of assuming r*Cols + c.
+func (m *Maze) index(r, c int) int {
+	return m.rowOffsets()[r] + c
+}
+
+// cellAt is the inverse of index.
+func (m *Maze) cellAt(id int) (int, int) {
+	offsets := m.rowOffsets()
+	if m.rectangular {
+		return id / m.Cols, id % m.Cols
+	}
+	r := sort.SearchInts(offsets, id+1) - 1
+	return r, id - offsets[r]
+}
+
+// cellCount is the number of cells in the grid, masked or not.
+func (m *Maze) cellCount() int {
+	offsets := m.rowOffsets()
+	return offsets[len(offsets)-1]
+}
+
+// isRectangular reports whether every row has Cols cells.
+func (m *Maze) isRectangular() bool {
+	m.rowOffsets()
+	return m.rectangular
+}
+
+// IsSquare reports whether the maze uses the square topology, which the
+// image-based and division generators depend on.
+func (m *Maze) IsSquare() bool {
+	_, ok := m.topo().(squareTopology)
+	return ok
+}
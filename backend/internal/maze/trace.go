@@ -0,0 +1,68 @@
+package maze
+
+// Trace event types emitted while generating.
+const (
+	TraceVisit     = "visit"     // a cell joined the maze or was walked onto
+	TraceRemove    = "remove"    // the wall between two cells was removed
+	TraceAdd       = "add"       // the wall between two cells was added
+	TraceMerge     = "merge"     // Kruskal's DSU merged the sets of two cells
+	TraceBacktrack = "backtrack" // a cell ran out of unvisited neighbours
+	TraceClear     = "clear"     // every interior wall was removed at once
+	TraceTunnel    = "tunnel"    // a passage was dug under the middle of three cells
+)
+
+// MaxTraceEvents caps how many events a single generation records, since
+// random-walk generators can take many millions of steps.
+const MaxTraceEvents = 1_000_000
+
+// TraceEvent is one step of a generator's work, recorded in order so the
+// frontend can animate how the maze was built.
+type TraceEvent struct {
+	Type  string   `json:"type"`
+	Cells [][2]int `json:"cells,omitempty"`
+}
+
+// EnableTrace makes subsequent generation record its steps in Trace.
+func (m *Maze) EnableTrace() {
+	m.tracing = true
+	m.Trace = []TraceEvent{}
+}
+
+// trace records an event that involves no cells when tracing is enabled.
+// It and the fixed-arity helpers below only build the cell slice once they
+// know the event will be kept, so generation without tracing never allocates.
+func (m *Maze) trace(kind string) {
+	if m.tracing {
+		m.record(kind, nil)
+	}
+}
+
+// trace1 records an event at one cell when tracing is enabled.
+func (m *Maze) trace1(kind string, a [2]int) {
+	if m.tracing {
+		m.record(kind, [][2]int{a})
+	}
+}
+
+// trace2 records an event between two cells when tracing is enabled.
+func (m *Maze) trace2(kind string, a, b [2]int) {
+	if m.tracing {
+		m.record(kind, [][2]int{a, b})
+	}
+}
+
+// trace3 records an event across three cells when tracing is enabled.
+func (m *Maze) trace3(kind string, a, b, c [2]int) {
+	if m.tracing {
+		m.record(kind, [][2]int{a, b, c})
+	}
+}
+
+// record appends an event unless the trace is already full.
+func (m *Maze) record(kind string, cells [][2]int) {
+	if len(m.Trace) >= MaxTraceEvents {
+		m.TraceTruncated = true
+		return
+	}
+	m.Trace = append(m.Trace, TraceEvent{Type: kind, Cells: cells})
+}
@@ -0,0 +1,145 @@
+package maze
+
+import (
+	"context"
+	"math/rand/v2"
+)
+
+// Wilson's and Aldous-Broder both sample uniformly from every possible
+// spanning tree of the grid, so they carry no texture bias of their own.
+
+// randomNeighbor picks one active neighbour of (r, c), ignoring walls.
+// The cell must have at least one.
+func (m *Maze) randomNeighbor(r, c int, rng *rand.Rand) (int, int) {
+	sides := m.topo().Sides()
+	for {
+		nr, nc, _ := m.neighbor(r, c, rng.IntN(sides))
+		if m.isActive(nr, nc) {
+			return nr, nc
+		}
+	}
+}
+
+// GenerateAldousBroder carves a maze with an unbiased random walk, opening
+// a passage whenever the walk first enters an unvisited cell.
+func (m *Maze) GenerateAldousBroder() {
+	m.generateAldousBroder(context.Background(), m.Rand())
+}
+
+// generateAldousBroder stops early if ctx is cancelled, since the walk can
+// take a long time to reach the last few cells of a large grid.
+func (m *Maze) generateAldousBroder(ctx context.Context, rng *rand.Rand) error {
+	m.initializeWallWeights(255)
+	total := m.cellCount()
+	if total == 0 {
+		return nil
+	}
+
+	labels, count := m.regions()
+	sizes := make([]int, count)
+	firsts := make([]int, count)
+	for i := len(labels) - 1; i >= 0; i-- {
+		if labels[i] >= 0 {
+			sizes[labels[i]]++
+			firsts[labels[i]] = i
+		}
+	}
+
+	// the walk is uniform from any starting cell, so separate pieces of a
+	// masked shape simply start from their first cell
+	visited := make([]bool, total)
+	for region := range count {
+		r, c := m.cellAt(firsts[region])
+		if count == 1 {
+			r, c = m.randomActiveCell(rng)
+		}
+		visited[m.index(r, c)] = true
+		m.trace1(TraceVisit, [2]int{r, c})
+
+		for steps, remaining := 0, sizes[region]-1; remaining > 0; steps++ {
+			if steps%4096 == 0 && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			nr, nc := m.randomNeighbor(r, c, rng)
+			m.trace1(TraceVisit, [2]int{nr, nc})
+			if !visited[m.index(nr, nc)] {
+				m.RemoveWalls(r, c, nr, nc)
+				visited[m.index(nr, nc)] = true
+				remaining--
+			}
+			r, c = nr, nc
+		}
+	}
+	return nil
+}
+
+// GenerateWilson carves a maze from loop-erased random walks, each walk
+// wandering until it hits the tree built so far.
+func (m *Maze) GenerateWilson() {
+	m.generateWilson(context.Background(), m.Rand())
+}
+
+// generateWilson stops early if ctx is cancelled, as the first walks on a
+// large grid can wander for a long time before reaching the tree.
+func (m *Maze) generateWilson(ctx context.Context, rng *rand.Rand) error {
+	m.initializeWallWeights(255)
+	total := m.cellCount()
+	if total == 0 {
+		return nil
+	}
+
+	inTree := make([]bool, total)
+	// next remembers the last exit taken from each cell during the current
+	// walk; overwriting it on revisits is what erases the loops.
+	next := make([]int, total)
+
+	// every piece of a masked shape needs its own root for walks to reach
+	labels, count := m.regions()
+	var order []int
+	if count == 1 {
+		root := rng.IntN(total)
+		for labels[root] < 0 {
+			root = rng.IntN(total)
+		}
+		inTree[root] = true
+		rr, rc := m.cellAt(root)
+		m.trace1(TraceVisit, [2]int{rr, rc})
+		order = rng.Perm(total)
+	} else {
+		order = rng.Perm(total)
+		rooted := make([]bool, count)
+		for _, i := range order {
+			if labels[i] >= 0 && !rooted[labels[i]] {
+				rooted[labels[i]] = true
+				inTree[i] = true
+				ir, ic := m.cellAt(i)
+				m.trace1(TraceVisit, [2]int{ir, ic})
+			}
+		}
+	}
+
+	for _, start := range order {
+		if inTree[start] || labels[start] < 0 {
+			continue
+		}
+
+		for steps, cur := 0, start; !inTree[cur]; steps, cur = steps+1, next[cur] {
+			if steps%4096 == 0 && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			cr, cc := m.cellAt(cur)
+			nr, nc := m.randomNeighbor(cr, cc, rng)
+			next[cur] = m.index(nr, nc)
+			m.trace1(TraceVisit, [2]int{nr, nc})
+		}
+
+		for cur := start; !inTree[cur]; cur = next[cur] {
+			inTree[cur] = true
+			nxt := next[cur]
+			cr, cc := m.cellAt(cur)
+			nr, nc := m.cellAt(nxt)
+			m.RemoveWalls(cr, cc, nr, nc)
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"math"
 	"runtime"
+	"slices"
 	"sync"
 )
 
@@ -177,4 +178,116 @@ func mapToWeights(nmsMags [][]float64, angles [][]float64, rows, cols, width, he
 		}
 	}
 	return weights
-}
\ No newline at end of file
+}
+
+// MaskFromImage turns a silhouette into a mask for the maze's grid, which
+// must be rectangular. Dark, opaque pixels are inside the shape; a cell is
+// kept when most of the pixels it covers are inside, and masked otherwise.
+// Multi-level mazes repeat the shape on every floor.
+func (m *Maze) MaskFromImage(r io.Reader) ([][]bool, error) {
+	if !m.isRectangular() {
+		return nil, fmt.Errorf("image masks need a rectangular grid, not %s", m.Topology)
+	}
+	rows, cols := m.Rows, m.Cols
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("mask image is empty")
+	}
+
+	inside := func(x, y int) bool {
+		cr, cg, cb, ca := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		if ca < 0x8000 {
+			return false
+		}
+		// un-premultiply before judging brightness
+		lum := (299*cr + 587*cg + 114*cb) / 1000 * 0xffff / ca
+		return lum < 0x8000
+	}
+
+	mask := make([][]bool, rows)
+	for row := range rows {
+		mask[row] = make([]bool, cols)
+		for col := range cols {
+			startY, endY := row*height/rows, max((row+1)*height/rows, row*height/rows+1)
+			startX, endX := col*width/cols, max((col+1)*width/cols, col*width/cols+1)
+
+			in, total := 0, 0
+			for y := startY; y < endY && y < height; y++ {
+				for x := startX; x < endX && x < width; x++ {
+					if inside(x, y) {
+						in++
+					}
+					total++
+				}
+			}
+			mask[row][col] = in*2 <= total
+		}
+	}
+
+	// every floor of a multi-level maze takes the same shape
+	for len(mask) < len(m.Grid) {
+		mask = append(mask, slices.Clone(mask[len(mask)-rows]))
+	}
+	return mask, nil
+}
+
+// TerrainFromImage turns an image into terrain costs for the maze's grid,
+// which must be rectangular. Each cell takes the average brightness of the
+// pixels it covers: light areas are road, darker ones mud and the darkest
+// water, with transparent pixels counting as light. Multi-level mazes
+// repeat the terrain on every floor.
+func (m *Maze) TerrainFromImage(r io.Reader) ([][]int, error) {
+	if !m.isRectangular() {
+		return nil, fmt.Errorf("image terrain needs a rectangular grid, not %s", m.Topology)
+	}
+	rows, cols := m.Rows, m.Cols
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("terrain image is empty")
+	}
+
+	// brightness composites each pixel over white, from 0 to 1
+	brightness := func(x, y int) float64 {
+		cr, cg, cb, ca := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		lum := float64(299*cr+587*cg+114*cb) / 1000
+		return (lum + float64(0xffff-ca)) / 0xffff
+	}
+
+	costs := make([][]int, rows)
+	for row := range rows {
+		costs[row] = make([]int, cols)
+		for col := range cols {
+			startY, endY := row*height/rows, max((row+1)*height/rows, row*height/rows+1)
+			startX, endX := col*width/cols, max((col+1)*width/cols, col*width/cols+1)
+
+			sum, total := 0.0, 0
+			for y := startY; y < endY && y < height; y++ {
+				for x := startX; x < endX && x < width; x++ {
+					sum += brightness(x, y)
+					total++
+				}
+			}
+			costs[row][col] = terrainCost(sum / float64(max(total, 1)))
+		}
+	}
+
+	// every floor of a multi-level maze takes the same terrain
+	for len(costs) < len(m.Grid) {
+		costs = append(costs, slices.Clone(costs[len(costs)-rows]))
+	}
+	return costs, nil
+}
@@ -0,0 +1,167 @@
+package maze
+
+import "math/rand/v2"
+
+// Weave mazes let passages cross under one another. The crossing cell
+// keeps its own walls for the passage running over it and is marked as a
+// Tunnel; the passage underneath shows up only on the cells either side,
+// whose walls open towards the crossing while the crossing's walls facing
+// them stay closed. Moving through such a wall passes under the crossing
+// and comes out on the far side.
+
+// DefaultWeaveDensity is the chance that an interior cell becomes a
+// crossing, if its surroundings allow one.
+const DefaultWeaveDensity = 0.3
+
+// passage returns the cell reached by leaving (r, c) through side, following
+// a tunnel straight under a crossing cell to the far side.
+func (m *Maze) passage(r, c, side int) (int, int, bool) {
+	if m.Grid[r][c].Walls[side] {
+		return 0, 0, false
+	}
+	nr, nc, _ := m.neighbor(r, c, side)
+	if !m.isActive(nr, nc) {
+		return 0, 0, false
+	}
+	if m.isUnder(nr, nc, r, c) {
+		nr, nc, _ = m.neighbor(nr, nc, side)
+		if !m.isActive(nr, nc) {
+			return 0, 0, false
+		}
+	}
+	return nr, nc, true
+}
+
+// isUnder reports whether entering (r, c) from the adjacent cell (fr, fc)
+// goes into the tunnel beneath it rather than onto the passage above.
+func (m *Maze) isUnder(r, c, fr, fc int) bool {
+	return m.Grid[r][c].Tunnel && m.Grid[r][c].Walls[m.sideTowards(r, c, fr, fc)]
+}
+
+// HasTunnels reports whether any passage runs under another.
+func (m *Maze) HasTunnels() bool {
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if m.Grid[r][c].Tunnel {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markTunnels restores the Tunnel flags from decoded walls: a crossing is
+// the only active cell whose active neighbour opens towards it while its
+// own wall on that side stays closed.
+func (m *Maze) markTunnels() {
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			cell := &m.Grid[r][c]
+			cell.Tunnel = false
+			if cell.Masked {
+				continue
+			}
+			for side, isWall := range cell.Walls {
+				if !isWall {
+					continue
+				}
+				nr, nc, _ := m.neighbor(r, c, side)
+				if m.isActive(nr, nc) && !m.Grid[nr][nc].Walls[m.sideTowards(nr, nc, r, c)] {
+					cell.Tunnel = true
+				}
+			}
+		}
+	}
+}
+
+// generateWeave lays crossings at random, each joining the cells around it
+// in two straight passages, one over and one under, and then joins
+// everything else with Kruskal's algorithm so the result is still a
+// perfect maze.
+func (m *Maze) generateWeave(density float64, rng *rand.Rand) {
+	m.initializeWallWeights(255)
+	dsu := NewDSU(m.cellCount())
+
+	for r := 1; r < m.Rows-1; r++ {
+		for c := 1; c < m.Cols-1; c++ {
+			if rng.Float64() < density {
+				m.addCrossing(r, c, dsu, rng)
+			}
+		}
+	}
+
+	var walls []Wall
+	for r := range m.Grid {
+		for c := range m.Grid[r] {
+			if !m.isActive(r, c) || m.Grid[r][c].Tunnel {
+				continue
+			}
+			// walls around a crossing are already decided
+			for _, side := range []int{1, 2} {
+				nr, nc, _ := m.neighbor(r, c, side)
+				if m.isActive(nr, nc) && !m.Grid[nr][nc].Tunnel {
+					walls = append(walls, Wall{R1: r, C1: c, R2: nr, C2: nc})
+				}
+			}
+		}
+	}
+	rng.Shuffle(len(walls), func(i, j int) {
+		walls[i], walls[j] = walls[j], walls[i]
+	})
+
+	for _, w := range walls {
+		id1, id2 := m.index(w.R1, w.C1), m.index(w.R2, w.C2)
+		if dsu.Find(id1) != dsu.Find(id2) {
+			m.RemoveWalls(w.R1, w.C1, w.R2, w.C2)
+			dsu.Union(id1, id2)
+			m.trace2(TraceMerge, [2]int{w.R1, w.C1}, [2]int{w.R2, w.C2})
+		}
+	}
+}
+
+// addCrossing turns (r, c) into a crossing if it and its four neighbours
+// are untouched so far and joining them cannot close a loop.
+func (m *Maze) addCrossing(r, c int, dsu *DSU, rng *rand.Rand) {
+	if !m.isActive(r, c) {
+		return
+	}
+	for _, isWall := range m.Grid[r][c].Walls {
+		if !isWall {
+			return
+		}
+	}
+
+	var around [4]Point
+	for side := range around {
+		nr, nc, _ := m.neighbor(r, c, side)
+		if !m.isActive(nr, nc) || m.Grid[nr][nc].Tunnel {
+			return
+		}
+		around[side] = Point{nr, nc}
+	}
+	id := func(p Point) int { return m.index(p[0], p[1]) }
+
+	// sides 0/2 and 1/3 are the vertical and horizontal pairs
+	over := rng.IntN(2)
+	a, b := around[over], around[over+2]
+	u, v := around[1-over], around[3-over]
+	if dsu.Find(id(a)) == dsu.Find(id(b)) || dsu.Find(id(u)) == dsu.Find(id(v)) {
+		return
+	}
+	for _, p := range []Point{a, b} {
+		if dsu.Find(id(p)) == dsu.Find(id(u)) || dsu.Find(id(p)) == dsu.Find(id(v)) {
+			return
+		}
+	}
+
+	m.RemoveWalls(r, c, a[0], a[1])
+	m.RemoveWalls(r, c, b[0], b[1])
+	dsu.Union(m.index(r, c), id(a))
+	dsu.Union(m.index(r, c), id(b))
+
+	m.Grid[r][c].Tunnel = true
+	m.Grid[u[0]][u[1]].Walls[m.sideTowards(u[0], u[1], r, c)] = false
+	m.Grid[v[0]][v[1]].Walls[m.sideTowards(v[0], v[1], r, c)] = false
+	dsu.Union(id(u), id(v))
+	m.trace3(TraceTunnel, u, [2]int{r, c}, v)
+}
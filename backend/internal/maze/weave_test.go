@@ -0,0 +1,45 @@
+package maze
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWeaveWeightsRoundTrip(t *testing.T) {
+	m := NewMaze(20, 20)
+	m.SetSeed(3)
+	gen, err := NewGenerator("weave", Params{"density": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.Generate(context.Background(), m, m.Rand()); err != nil {
+		t.Fatal(err)
+	}
+	m.SyncGridToWeights(nil)
+
+	restored := NewMaze(m.Rows, m.Cols)
+	if err := restored.DecodeWalls(m.EncodeWalls()); err != nil {
+		t.Fatal(err)
+	}
+	restored.ApplyWeights(m.Weights)
+	split := 0
+	for r := range m.Grid {
+		for c, cell := range m.Grid[r] {
+			for side, closed := range cell.Walls {
+				if m.splitWall(r, c, side) {
+					split++
+				}
+				want := 0
+				if closed {
+					want = 255
+				}
+				if got := restored.Grid[r][c].WallWeights[side]; got != want {
+					t.Errorf("cell (%d, %d) side %d restored with weight %d, want %d", r, c, side, got, want)
+				}
+			}
+		}
+	}
+	if split == 0 {
+		t.Fatal("no crossings to check")
+	}
+}
@@ -0,0 +1,115 @@
+package maze
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Wrap modes join opposite edges of the grid, so a passage leaving one
+// side comes back in on the other.
+const (
+	WrapNone     = ""
+	WrapCylinder = "cylinder" // left and right edges meet
+	WrapTorus    = "torus"    // left meets right and top meets bottom
+)
+
+// ErrUnsupportedWrap is returned when a grid cannot wrap in the requested
+// way.
+var ErrUnsupportedWrap = errors.New("unsupported wrap mode")
+
+// wrapTopology joins the edges of a rectangular base topology. Neighbours
+// that would fall off a joined edge come back from the opposite one.
+type wrapTopology struct {
+	base       Topology
+	rows, cols int
+	vertical   bool // top and bottom meet as well
+}
+
+func (t wrapTopology) Name() string                    { return t.base.Name() }
+func (t wrapTopology) Sides() int                      { return t.base.Sides() }
+func (t wrapTopology) SideNames() []string             { return t.base.SideNames() }
+func (t wrapTopology) RowLengths(rows, cols int) []int { return t.base.RowLengths(rows, cols) }
+
+func (t wrapTopology) Neighbor(m *Maze, r, c, side int) (int, int) {
+	nr, nc := t.base.Neighbor(m, r, c, side)
+	nc = (nc + t.cols) % t.cols
+	if t.vertical {
+		nr = (nr + t.rows) % t.rows
+	}
+	return nr, nc
+}
+
+// Distance is the base distance to the nearest copy of b, as if the grid
+// were tiled across every joined edge.
+func (t wrapTopology) Distance(a, b Point) int {
+	best := t.base.Distance(a, b)
+	dr := []int{0}
+	if t.vertical {
+		dr = []int{0, -t.rows, t.rows}
+	}
+	for _, r := range dr {
+		for _, c := range []int{0, -t.cols, t.cols} {
+			best = min(best, t.base.Distance(a, Point{b[0] + r, b[1] + c}))
+		}
+	}
+	return best
+}
+
+// PlaceStartEnd enters through the top edge and leaves through the bottom
+// on a cylinder. A torus has no edges, so its entrance and exit are the
+// two cells farthest apart.
+func (t wrapTopology) PlaceStartEnd(m *Maze, rng *rand.Rand) (Point, Point) {
+	c := rng.IntN(t.cols)
+	if t.vertical {
+		r := rng.IntN(t.rows)
+		return Point{r, c}, Point{(r + t.rows/2) % t.rows, (c + t.cols/2) % t.cols}
+	}
+	return Point{0, c}, Point{t.rows - 1, rng.IntN(t.cols)}
+}
+
+// SetWrap joins the grid's edges according to mode. It must be called on a
+// fresh maze before generation. Only rectangular grids can wrap, and each
+// joined direction needs at least three cells so a cell never meets the
+// same neighbour on both sides.
+func (m *Maze) SetWrap(mode string) error {
+	switch mode {
+	case WrapNone, WrapCylinder, WrapTorus:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedWrap, mode)
+	}
+	if mode == WrapNone {
+		m.Wrap, m.topology = mode, nil
+		return nil
+	}
+
+	switch m.shape().(type) {
+	case squareTopology:
+	case hexTopology:
+		// odd rows are shifted, so the rows only line up again across an
+		// even number of them
+		if mode == WrapTorus && m.Rows%2 != 0 {
+			return fmt.Errorf("%w: a hex torus needs an even number of rows", ErrUnsupportedWrap)
+		}
+	default:
+		return fmt.Errorf("%w: %s grids cannot wrap", ErrUnsupportedWrap, m.Topology)
+	}
+	if m.Cols < 3 || (mode == WrapTorus && m.Rows < 3) {
+		return fmt.Errorf("%w: %s needs at least 3 cells in each wrapped direction", ErrUnsupportedWrap, mode)
+	}
+
+	m.Wrap, m.topology = mode, nil
+	return nil
+}
+
+// wraps reports whether moving from (r, c) through side crosses a joined
+// edge, and where the neighbour would sit if the grid carried on.
+func (m *Maze) wraps(r, c, side int) (int, int, bool) {
+	t, ok := m.floorTopo().(wrapTopology)
+	if !ok {
+		return 0, 0, false
+	}
+	nr, nc := t.base.Neighbor(m, r, c, side)
+	crosses := nc < 0 || nc >= t.cols || (t.vertical && (nr < 0 || nr >= t.rows))
+	return nr, nc, crosses
+}
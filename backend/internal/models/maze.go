@@ -8,14 +8,25 @@ type Maze struct {
     ID          string    `gorm:"primaryKey" json:"id"`
     CreatorID   *string   `gorm:"type:uuid" json:"creator_id"`
     WeightsJSON string    `gorm:"type:jsonb;not null" json:"weights_json"`
+    WallsData   string    `gorm:"type:text" json:"walls_data"`
+    MaskData    string    `gorm:"type:text" json:"mask_data"`
+    FeaturesData string   `gorm:"type:text" json:"features_data"`
+    PortalsData string    `gorm:"type:text" json:"portals_data"`
+    Algorithm   string    `json:"algorithm"`
+    Topology    string    `json:"topology"`
+    Levels      int       `json:"levels"`
+    Wrap        string    `json:"wrap"`
+    Placement   string    `json:"placement"`
     Thumbnail   string    `gorm:"type:text" json:"thumbnail"`
     Rows        int       `gorm:"not null" json:"rows"`
     Cols        int       `gorm:"not null" json:"cols"`
+    Seed        int64     `json:"seed"`
     StartRow    int       `gorm:"not null" json:"start_row"`
     StartCol    int       `gorm:"not null" json:"start_col"`
     EndRow      int       `gorm:"not null" json:"end_row"`
     EndCol      int       `gorm:"not null" json:"end_col"`
     Complexity  float64   `json:"complexity"`
     DeadEnds    int       `json:"dead_ends"`
+    Loops       int       `json:"loops"`
     CreatedAt   time.Time `json:"created_at"`
 }
\ No newline at end of file
@@ -3,6 +3,52 @@ import { useState, useRef } from "react";
 import AlgorithmSelect from "@/components/maze/AlgorithmSelect";
 import GridDimensionsInput from "@/components/maze/GridDimensionsInput";
 
+const TOPOLOGIES = [
+  { id: "square", label: "SQUARE" },
+  { id: "hex", label: "HEXAGONAL" },
+  { id: "polar", label: "CIRCULAR" },
+];
+
+const LEVELS = [1, 2, 3, 4].map((n) => ({ id: String(n), label: `${n}F` }));
+
+const WRAPS = [
+  { id: "", label: "FLAT" },
+  { id: "cylinder", label: "CYLINDER" },
+  { id: "torus", label: "TORUS" },
+];
+
+const PLACEMENTS = [
+  { id: "random", label: "RANDOM" },
+  { id: "farthest", label: "FARTHEST" },
+  { id: "interior", label: "INSIDE" },
+  { id: "corners", label: "CORNERS" },
+];
+
+const KEYS = [0, 1, 2, 3].map((n) => ({
+  id: String(n),
+  label: n === 0 ? "NONE" : `${n}`,
+}));
+
+const TERRAINS = [
+  { id: "", label: "NONE" },
+  { id: "noise", label: "MUD_AND_WATER" },
+];
+
+const DIFFICULTIES = [
+  { id: "", label: "ANY" },
+  { id: "easy", label: "EASY" },
+  { id: "hard", label: "HARD" },
+];
+
+// Solution length bounds for each difficulty, scaled to the grid so the
+// server can find a match within its search budget.
+function solutionBounds(difficulty: string, rows: number, cols: number) {
+  const span = rows + cols;
+  if (difficulty === "easy") return { max_solution: span };
+  if (difficulty === "hard") return { min_solution: Math.round(1.5 * span) };
+  return {};
+}
+
 interface GenerateControlsProps {
   genType: string;
   setGenType: (val: string) => void;
@@ -29,6 +75,13 @@ export default function GenerateControls({
   algorithms,
 }: GenerateControlsProps) {
   const [isDragging, setIsDragging] = useState(false);
+  const [topology, setTopology] = useState("square");
+  const [levels, setLevels] = useState("1");
+  const [wrap, setWrap] = useState("");
+  const [difficulty, setDifficulty] = useState("");
+  const [placement, setPlacement] = useState("random");
+  const [keys, setKeys] = useState("0");
+  const [terrain, setTerrain] = useState("");
   const fileInputRef = useRef<HTMLInputElement>(null);
 
   // Clamping utility to enforce 2-300 range
@@ -64,7 +117,7 @@ export default function GenerateControls({
         />
       </div>
 
-      <div className="col-span-4 space-y-2">
+      <div className="col-span-2 space-y-2">
         <label className="block font-bold uppercase tracking-widest text-[9px]">
           Algorithm
         </label>
@@ -75,8 +128,84 @@ export default function GenerateControls({
         />
       </div>
 
+      <div className="col-span-2 space-y-2">
+        <label className="block font-bold uppercase tracking-widest text-[9px]">
+          Cell_Shape
+        </label>
+        <AlgorithmSelect
+          value={topology}
+          onChange={setTopology}
+          options={TOPOLOGIES}
+        />
+        <input type="hidden" name="topology" value={topology} />
+      </div>
+
+      <div className="col-span-1 space-y-2">
+        <label className="block font-bold uppercase tracking-widest text-[9px]">
+          Levels
+        </label>
+        <AlgorithmSelect value={levels} onChange={setLevels} options={LEVELS} />
+        <input type="hidden" name="levels" value={levels} />
+      </div>
+
+      <div className="col-span-1 space-y-2">
+        <label className="block font-bold uppercase tracking-widest text-[9px]">
+          Wrap
+        </label>
+        <AlgorithmSelect value={wrap} onChange={setWrap} options={WRAPS} />
+        <input type="hidden" name="wrap" value={wrap} />
+      </div>
+
+      <div className="col-span-1 space-y-2">
+        <label className="block font-bold uppercase tracking-widest text-[9px]">
+          Exits
+        </label>
+        <AlgorithmSelect
+          value={placement}
+          onChange={setPlacement}
+          options={PLACEMENTS}
+        />
+        <input type="hidden" name="placement" value={placement} />
+      </div>
+
+      <div className="col-span-1 space-y-2">
+        <label className="block font-bold uppercase tracking-widest text-[9px]">
+          Keys
+        </label>
+        <AlgorithmSelect value={keys} onChange={setKeys} options={KEYS} />
+        <input type="hidden" name="keys" value={keys} />
+      </div>
+
+      <div className="col-span-1 space-y-2">
+        <label className="block font-bold uppercase tracking-widest text-[9px]">
+          Difficulty
+        </label>
+        <AlgorithmSelect
+          value={difficulty}
+          onChange={setDifficulty}
+          options={DIFFICULTIES}
+        />
+        {Object.entries(solutionBounds(difficulty, dims.rows, dims.cols)).map(
+          ([name, value]) => (
+            <input key={name} type="hidden" name={name} value={value} />
+          )
+        )}
+      </div>
+
+      <div className="col-span-2 space-y-2">
+        <label className="block font-bold uppercase tracking-widest text-[9px]">
+          Terrain
+        </label>
+        <AlgorithmSelect
+          value={terrain}
+          onChange={setTerrain}
+          options={TERRAINS}
+        />
+        <input type="hidden" name="terrain" value={terrain} />
+      </div>
+
       <div
-        className={`col-span-5 space-y-2 transition-all ${
+        className={`col-span-3 space-y-2 transition-all ${
           genType === "image"
             ? "opacity-100"
             : "opacity-0 pointer-events-none hidden"
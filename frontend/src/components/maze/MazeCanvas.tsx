@@ -3,9 +3,43 @@ import { useEffect, useRef, useState, useMemo } from "react";
 import { MazeData } from "@/types";
 import { useMazeCanvas } from "@/hooks/useMazeCanvas";
 import { renderMazeImage } from "@/lib/api";
+import {
+  addCellShape,
+  addOneWays,
+  addStairs,
+  addWall,
+  addWeaveCell,
+  addWrapMarkers,
+  cellCenter,
+  hasSide,
+} from "@/lib/geometry";
 
 const PADDING = 800;
 
+// Key d and door d share KEY_COLORS[d - 1], matching the backend renderer.
+const KEY_COLORS = [
+  "#e6a000",
+  "#c828a0",
+  "#00a0aa",
+  "#7846c8",
+  "#d25a1e",
+  "#5a961e",
+  "#2850a0",
+  "#966e46",
+];
+
+// Both ends of portal pair i are ringed in PORTAL_COLORS[i].
+const PORTAL_COLORS = [
+  "#00aaff",
+  "#ff50aa",
+  "#6ed200",
+  "#965aff",
+  "#ff8c00",
+  "#00c8aa",
+  "#dc143c",
+  "#c8b400",
+];
+
 interface MazeCanvasProps {
   maze: MazeData;
   showSave?: boolean;
@@ -128,15 +162,10 @@ export default function MazeCanvas({
     if (!dynamicCellSize || !highlights) return path;
     const currentBatch = highlights.slice(0, Math.floor(visibleHighlights));
     currentBatch.forEach(([r, c]) => {
-      path.rect(
-        c * dynamicCellSize,
-        r * dynamicCellSize,
-        dynamicCellSize,
-        dynamicCellSize
-      );
+      addCellShape(path, maze, r, c, dynamicCellSize);
     });
     return path;
-  }, [visibleHighlights, highlights, dynamicCellSize]);
+  }, [visibleHighlights, highlights, dynamicCellSize, maze]);
 
   useEffect(() => {
     const canvas = canvasRef.current;
@@ -158,6 +187,21 @@ export default function MazeCanvas({
     const sPoint = overrideStart || maze.start;
     const ePoint = overrideEnd || maze.end;
 
+    // costly terrain is tinted under everything else, matching the backend
+    const mud = new Path2D(),
+      water = new Path2D();
+    maze.grid.forEach((row, r) =>
+      row.forEach((cell, c) => {
+        const cost = cell.cost ?? 0;
+        if (cell.masked || cost <= 1) return;
+        addCellShape(cost >= 5 ? water : mud, maze, r, c, cellSize);
+      })
+    );
+    ctx.fillStyle = "#d2b48c";
+    ctx.fill(mud);
+    ctx.fillStyle = "#a0c8f0";
+    ctx.fill(water);
+
     ctx.fillStyle = "rgba(113, 113, 122, 0.4)";
     ctx.fill(highlightPath);
 
@@ -172,8 +216,7 @@ export default function MazeCanvas({
         Math.floor(visibleSolutionStep)
       );
       currentPath.forEach(([r, c], idx) => {
-        const x = c * cellSize + cellSize / 2;
-        const y = r * cellSize + cellSize / 2;
+        const [x, y] = cellCenter(maze, r, c, cellSize);
         if (idx === 0) ctx.moveTo(x, y);
         else ctx.lineTo(x, y);
       });
@@ -184,49 +227,39 @@ export default function MazeCanvas({
       w >= 255
         ? "black"
         : `rgb(${Math.floor(230 - w * (230 / 255))},${Math.floor(230 - w * (230 / 255))},${Math.floor(230 - w * (230 / 255))})`;
-    ctx.fillStyle = "#90ee90";
-    ctx.fillRect(
-      sPoint[1] * cellSize,
-      sPoint[0] * cellSize,
-      cellSize,
-      cellSize
-    );
-    ctx.fillStyle = "#ff6347";
-    ctx.fillRect(
-      ePoint[1] * cellSize,
-      ePoint[0] * cellSize,
-      cellSize,
-      cellSize
-    );
+    const fillCell = ([r, c]: [number, number], color: string) => {
+      const shape = new Path2D();
+      addCellShape(shape, maze, r, c, cellSize);
+      ctx.fillStyle = color;
+      ctx.fill(shape);
+    };
+    fillCell(sPoint, "#90ee90");
+    fillCell(ePoint, "#ff6347");
 
     const wallBatches: Record<string, Path2D> = {};
-    for (let r = 0; r < maze.rows; r++) {
-      for (let c = 0; c < maze.cols; c++) {
-        const x = c * cellSize,
-          y = r * cellSize;
+    const stairs = new Path2D();
+    const wrapMarkers = new Path2D();
+    const oneWays = new Path2D();
+    const weave = maze.grid.some((row) => row.some((cell) => cell.tunnel));
+    for (let r = 0; r < maze.grid.length; r++) {
+      for (let c = 0; c < maze.grid[r].length; c++) {
+        if (maze.grid[r][c].masked) continue;
+        addOneWays(oneWays, maze, r, c, cellSize);
+        if (weave) {
+          const color = getWallColor(maze.grid[r][c].wall_weights[0]);
+          if (!wallBatches[color]) wallBatches[color] = new Path2D();
+          addWeaveCell(wallBatches[color], maze, r, c, cellSize);
+          continue;
+        }
         maze.grid[r][c].walls.forEach((w, i) => {
-          if (w) {
+          if (w && hasSide(maze, r, i)) {
             const color = getWallColor(maze.grid[r][c].wall_weights[i]);
             if (!wallBatches[color]) wallBatches[color] = new Path2D();
-            const p = wallBatches[color];
-            if (i === 0) {
-              p.moveTo(x, y);
-              p.lineTo(x + cellSize, y);
-            }
-            if (i === 1) {
-              p.moveTo(x + cellSize, y);
-              p.lineTo(x + cellSize, y + cellSize);
-            }
-            if (i === 2) {
-              p.moveTo(x, y + cellSize);
-              p.lineTo(x + cellSize, y + cellSize);
-            }
-            if (i === 3) {
-              p.moveTo(x, y);
-              p.lineTo(x, y + cellSize);
-            }
+            addWall(wallBatches[color], maze, r, c, i, cellSize);
           }
         });
+        addStairs(stairs, maze, r, c, cellSize);
+        addWrapMarkers(wrapMarkers, maze, r, c, cellSize);
       }
     }
     ctx.lineWidth = cellSize > 5 ? 1 : 0.5;
@@ -234,6 +267,42 @@ export default function MazeCanvas({
       ctx.strokeStyle = color;
       ctx.stroke(path);
     });
+    ctx.strokeStyle = "#1e64dc";
+    ctx.stroke(stairs);
+    ctx.strokeStyle = "#009650";
+    ctx.stroke(wrapMarkers);
+    ctx.strokeStyle = "#dc3c1e";
+    ctx.stroke(oneWays);
+
+    // doors are solid blocks, keys small discs in the same colour
+    maze.grid.forEach((row, r) =>
+      row.forEach((cell, c) => {
+        if (cell.masked || (!cell.key && !cell.door)) return;
+        const [cx, cy] = cellCenter(maze, r, c, cellSize);
+        if (cell.door) {
+          const s = cellSize * 0.3;
+          ctx.fillStyle = KEY_COLORS[cell.door - 1];
+          ctx.fillRect(cx - s, cy - s, 2 * s, 2 * s);
+        }
+        if (cell.key) {
+          ctx.fillStyle = KEY_COLORS[cell.key - 1];
+          ctx.beginPath();
+          ctx.arc(cx, cy, cellSize / 5, 0, 2 * Math.PI);
+          ctx.fill();
+        }
+      })
+    );
+
+    ctx.lineWidth = Math.max(cellSize * 0.12, 1);
+    maze.portals?.forEach((pair, i) =>
+      pair.forEach(([r, c]) => {
+        const [cx, cy] = cellCenter(maze, r, c, cellSize);
+        ctx.strokeStyle = PORTAL_COLORS[i % PORTAL_COLORS.length];
+        ctx.beginPath();
+        ctx.arc(cx, cy, cellSize * 0.29, 0, 2 * Math.PI);
+        ctx.stroke();
+      })
+    );
     ctx.restore();
   }, [
     maze,
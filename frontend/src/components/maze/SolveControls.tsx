@@ -85,7 +85,7 @@ export default function SolveControls({
           }
           min={0}
           max={{
-            rows: (activeMaze?.rows || 1) - 1,
+            rows: (activeMaze?.grid.length || 1) - 1,
             cols: (activeMaze?.cols || 1) - 1,
           }}
         />
@@ -102,7 +102,7 @@ export default function SolveControls({
           onUpdate={(dim, val) =>
             setEndPoint([
               dim === "rows"
-                ? validate(val, activeMaze?.rows || 1)
+                ? validate(val, activeMaze?.grid.length || 1)
                 : endPoint[0],
               dim === "cols"
                 ? validate(val, activeMaze?.cols || 1)
@@ -6,17 +6,25 @@ import {
   savePreferences,
   loadPreferences,
 } from "@/lib/db";
-import { updateThumbnail } from "@/lib/api";
+import { updateThumbnail, getAlgorithms } from "@/lib/api";
 import MazeCanvas from "@/components/maze/MazeCanvas";
 import GenerateControls from "@/components/maze/GenerateControls";
 import { useImageDimensions } from "@/hooks/useImageDimensions";
 import { useMazeGeneration } from "@/hooks/useMazeGeneration";
 import { MazeData } from "@/types";
 
+// Fallback options until the server catalog has loaded.
 const ALGORITHMS = [
   { id: "image", label: "IMAGE_KRUSKAL" },
   { id: "kruskal", label: "RANDOM_KRUSKAL" },
   { id: "recursive", label: "DFS_BACKTRACKER" },
+  { id: "prim", label: "RANDOM_PRIM" },
+  { id: "wilson", label: "UNIFORM_WILSON" },
+  { id: "aldous-broder", label: "UNIFORM_ALDOUS_BRODER" },
+  { id: "growing-tree", label: "GROWING_TREE" },
+  { id: "division", label: "RECURSIVE_DIVISION" },
+  { id: "weave", label: "KRUSKAL_WEAVE" },
+  { id: "dungeon", label: "ROOMS_AND_CORRIDORS" },
 ];
 
 export default function CreatePage() {
@@ -28,6 +36,22 @@ export default function CreatePage() {
   const [selectedFile, setSelectedFile] = useState<File | null>(null);
   const { dims, updateDim, clampDimensions, handleImageChange } =
     useImageDimensions();
+  const [algorithms, setAlgorithms] = useState(ALGORITHMS);
+
+  useEffect(() => {
+    getAlgorithms()
+      .then((catalog) =>
+        setAlgorithms(
+          catalog.generators.map((a) => ({
+            id: a.name,
+            label:
+              ALGORITHMS.find((o) => o.id === a.name)?.label ??
+              a.name.toUpperCase().replace(/-/g, "_"),
+          }))
+        )
+      )
+      .catch(() => {});
+  }, []);
 
   useEffect(() => {
     const init = async () => {
@@ -139,7 +163,7 @@ export default function CreatePage() {
         selectedFile={selectedFile}
         onSubmit={handleSubmit}
         loading={loading}
-        algorithms={ALGORITHMS}
+        algorithms={algorithms}
         isSubmitDisabled={loading || (genType === "image" && !selectedFile)}
       />
 
@@ -9,11 +9,13 @@ import {
 } from "@/lib/db";
 import MazeCanvas from "@/components/maze/MazeCanvas";
 import SolveControls from "@/components/maze/SolveControls";
-import { solveMaze, getMazeById } from "@/lib/api";
+import { solveMaze, getMazeById, getAlgorithms } from "@/lib/api";
 import { MazeData } from "@/types";
 
+// Fallback options until the server catalog has loaded.
 const SOLVE_ALGORITHMS = [
   { id: "astar", label: "A*_SEARCH" },
+  { id: "dijkstra", label: "DIJKSTRA" },
   { id: "bfs", label: "BREADTH_FIRST" },
   { id: "greedy", label: "GREEDY_SEARCH" },
 ];
@@ -44,12 +46,29 @@ function SolveCore() {
   const [isSolving, setIsSolving] = useState(false);
   const [isAnimating, setIsAnimating] = useState(false);
   const [mazeId, setMazeId] = useState(urlId || "");
+  const [algorithms, setAlgorithms] = useState(SOLVE_ALGORITHMS);
+
+  useEffect(() => {
+    getAlgorithms()
+      .then((catalog) =>
+        setAlgorithms(
+          catalog.solvers.map((a) => ({
+            id: a.name,
+            label:
+              SOLVE_ALGORITHMS.find((o) => o.id === a.name)?.label ??
+              a.name.toUpperCase().replace(/-/g, "_"),
+          }))
+        )
+      )
+      .catch(() => {});
+  }, []);
 
   const [startPoint, setStartPoint] = useState<[number, number]>([0, 0]);
   const [endPoint, setEndPoint] = useState<[number, number]>([0, 0]);
   const [solution, setSolution] = useState<{
     visited: [number, number][];
     path: [number, number][];
+    cost?: number;
   } | null>(null);
 
   useEffect(() => {
@@ -149,7 +168,7 @@ function SolveCore() {
           activeMaze={activeMaze}
           isSolving={isSolving}
           isAnimating={isAnimating}
-          algorithms={SOLVE_ALGORITHMS}
+          algorithms={algorithms}
           validate={(v, m) => Math.min(Math.max(0, v), m - 1)}
         />
       </div>
@@ -174,6 +193,7 @@ function SolveCore() {
             </span>
             <span>VISITED: {solution?.visited?.length ?? "--"}</span>
             <span>PATH: {solution?.path?.length ?? "--"}</span>
+            <span>COST: {solution?.cost ?? "--"}</span>
           </div>
         </div>
 
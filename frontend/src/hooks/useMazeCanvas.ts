@@ -1,6 +1,7 @@
 "use client";
 import { useState, useRef, useEffect, useCallback } from "react";
 import { MazeData } from "@/types";
+import { fitCellSize, mazeSize } from "@/lib/geometry";
 
 export function useMazeCanvas(maze: MazeData | null) {
   const containerRef = useRef<HTMLDivElement>(null);
@@ -16,8 +17,7 @@ export function useMazeCanvas(maze: MazeData | null) {
       if (!container || !maze || cellSize === 0) return;
       const viewW = container.clientWidth;
       const viewH = container.clientHeight;
-      const mazeW = maze.cols * cellSize;
-      const mazeH = maze.rows * cellSize;
+      const { width: mazeW, height: mazeH } = mazeSize(maze, cellSize);
 
       setTransform({
         s: 1,
@@ -101,10 +101,7 @@ export function useMazeCanvas(maze: MazeData | null) {
       const parent = containerRef.current?.closest("section");
       if (!parent) return;
       setDynamicCellSize(
-        Math.min(
-          (parent.clientWidth - 128) / maze.cols,
-          (parent.clientHeight - 128) / maze.rows
-        )
+        fitCellSize(maze, parent.clientWidth - 128, parent.clientHeight - 128)
       );
     };
     updateSize();
@@ -1,4 +1,4 @@
-import { Post, Comment, Maze, User } from "@/types";
+import { Post, Comment, Maze, User, AlgorithmCatalog } from "@/types";
 
 const BASE_URL = process.env.NEXT_PUBLIC_API_URL;
 
@@ -64,6 +64,12 @@ export async function generateMaze(
   return res.json();
 }
 
+export async function getAlgorithms(): Promise<AlgorithmCatalog> {
+  const res = await fetch(`${BASE_URL}/api/maze/algorithms`);
+  if (!res.ok) throw new Error("ALGORITHM_FETCH_FAILED");
+  return res.json();
+}
+
 export async function renderMazeImage(mazeData: any): Promise<Blob> {
   const response = await fetch(`${BASE_URL}/api/maze/render`, {
     method: "POST",
@@ -0,0 +1,369 @@
+import { MazeData } from "@/types";
+
+// Cell geometry for each maze topology, matching the backend renderer.
+// Hex mazes use pointy-topped cells with odd rows shifted right, and
+// cellSize is the distance between opposite flat sides. Polar mazes are
+// rings of depth cellSize around the centre, with columns running
+// clockwise from north and rings growing outward. Multi-level mazes stack
+// their floors in the grid, rows floors at a time, and are drawn side by
+// side one cell apart. Square and hex mazes may wrap, joining left and
+// right edges on a cylinder and top and bottom as well on a torus.
+
+type Pt = [number, number];
+
+const hexRadius = (cellSize: number) => cellSize / Math.sqrt(3);
+
+const levels = (maze: MazeData) => maze.levels ?? 1;
+
+// Sides of a cell on its own floor; stairs up and down come after them.
+const floorSides = (maze: MazeData) =>
+  maze.topology === "hex" ? 6 : maze.topology === "polar" ? 5 : 4;
+
+// Pixel size of a single floor at the given cell size.
+function floorSize(maze: MazeData, cellSize: number) {
+  if (maze.topology === "polar") {
+    const size = 2 * maze.rows * cellSize;
+    return { width: size, height: size };
+  }
+  if (maze.topology === "hex") {
+    return {
+      width: cellSize * (maze.cols + 0.5),
+      height: hexRadius(cellSize) * (1.5 * maze.rows + 0.5),
+    };
+  }
+  return { width: maze.cols * cellSize, height: maze.rows * cellSize };
+}
+
+// Pixel size of the whole maze at the given cell size.
+export function mazeSize(maze: MazeData, cellSize: number) {
+  const floor = floorSize(maze, cellSize);
+  const n = levels(maze);
+  return { width: n * floor.width + (n - 1) * cellSize, height: floor.height };
+}
+
+// Largest cell size that fits the maze inside width x height.
+export function fitCellSize(maze: MazeData, width: number, height: number) {
+  const unit = mazeSize(maze, 1);
+  return Math.min(width / unit.width, height / unit.height);
+}
+
+// Splits a grid row into the row on its floor and that floor's left edge.
+function locate(maze: MazeData, r: number, cellSize: number) {
+  const level = Math.floor(r / maze.rows);
+  return {
+    row: r - level * maze.rows,
+    x: level * (floorSize(maze, cellSize).width + cellSize),
+  };
+}
+
+export function cellCenter(
+  maze: MazeData,
+  r: number,
+  c: number,
+  cellSize: number
+): Pt {
+  const { row, x } = locate(maze, r, cellSize);
+  if (maze.topology === "polar") {
+    const n = maze.grid[r].length;
+    return polarPoint(maze, x, (row + 0.5) * cellSize, (2 * Math.PI * (c + 0.5)) / n, cellSize);
+  }
+  if (maze.topology === "hex") {
+    return [
+      x + cellSize * (c + 0.5 + 0.5 * (row & 1)),
+      hexRadius(cellSize) * (1 + 1.5 * row),
+    ];
+  }
+  return [x + c * cellSize + cellSize / 2, row * cellSize + cellSize / 2];
+}
+
+// Corners of a cell in clockwise order; wall i runs from corner i to
+// corner i + 1.
+export function cellCorners(
+  maze: MazeData,
+  r: number,
+  c: number,
+  cellSize: number
+): Pt[] {
+  if (maze.topology === "hex") {
+    const [cx, cy] = cellCenter(maze, r, c, cellSize);
+    const radius = hexRadius(cellSize);
+    return Array.from({ length: 6 }, (_, i) => {
+      const angle = (Math.PI / 180) * (60 * i - 90);
+      return [cx + radius * Math.cos(angle), cy + radius * Math.sin(angle)];
+    });
+  }
+  const { row, x: left } = locate(maze, r, cellSize);
+  const x = left + c * cellSize,
+    y = row * cellSize;
+  return [
+    [x, y],
+    [x + cellSize, y],
+    [x + cellSize, y + cellSize],
+    [x, y + cellSize],
+  ];
+}
+
+// Point at a radius and a clockwise angle from north, with the floor
+// whose left edge is at x centred in its bounding square.
+function polarPoint(
+  maze: MazeData,
+  x: number,
+  radius: number,
+  angle: number,
+  cellSize: number
+): Pt {
+  const centre = maze.rows * cellSize;
+  return [x + centre + radius * Math.sin(angle), centre - radius * Math.cos(angle)];
+}
+
+// Angular span of a polar cell; canvas arcs measure from east, so callers
+// subtract a quarter turn.
+function polarSpan(maze: MazeData, r: number, c: number) {
+  const n = maze.grid[r].length;
+  return [(2 * Math.PI * c) / n, (2 * Math.PI * (c + 1)) / n];
+}
+
+// Whether the outer ring splits a polar cell into two outward walls.
+const polarSplits = (maze: MazeData, row: number) =>
+  row < maze.rows - 1 && maze.grid[row + 1].length > maze.grid[row].length;
+
+// Whether a cell has the given side on its floor plan; polar cells lack an
+// inward wall in the innermost ring and a second outward wall unless they
+// split. Stairs are not walls and are marked with addStairs instead.
+export function hasSide(maze: MazeData, r: number, side: number) {
+  if (side >= floorSides(maze)) return false;
+  if (maze.topology !== "polar") return true;
+  const row = r % maze.rows;
+  if (side === 0) return row > 0;
+  if (side === 3) return polarSplits(maze, row);
+  return true;
+}
+
+// Traces wall side of a cell onto path.
+export function addWall(
+  path: Path2D,
+  maze: MazeData,
+  r: number,
+  c: number,
+  side: number,
+  cellSize: number
+) {
+  if (maze.topology === "polar") {
+    const { row, x } = locate(maze, r, cellSize);
+    const centre = maze.rows * cellSize;
+    const inner = row * cellSize,
+      outer = (row + 1) * cellSize;
+    const [t0, t1] = polarSpan(maze, r, c);
+    const mid = (t0 + t1) / 2;
+    const q = Math.PI / 2;
+    const arc = (radius: number, from: number, to: number) => {
+      path.moveTo(...polarPoint(maze, x, radius, from, cellSize));
+      path.arc(x + centre, centre, radius, from - q, to - q);
+    };
+    const radial = (angle: number) => {
+      path.moveTo(...polarPoint(maze, x, inner, angle, cellSize));
+      path.lineTo(...polarPoint(maze, x, outer, angle, cellSize));
+    };
+    if (side === 0) arc(inner, t0, t1);
+    else if (side === 1) radial(t1);
+    else if (side === 2) arc(outer, t0, polarSplits(maze, row) ? mid : t1);
+    else if (side === 3) arc(outer, mid, t1);
+    else radial(t0);
+    return;
+  }
+  const corners = cellCorners(maze, r, c, cellSize);
+  path.moveTo(...corners[side]);
+  path.lineTo(...corners[(side + 1) % corners.length]);
+}
+
+// Marks open stairs with a chevron pointing up or down; a cell with both
+// reads as a diamond.
+export function addStairs(
+  path: Path2D,
+  maze: MazeData,
+  r: number,
+  c: number,
+  cellSize: number
+) {
+  const walls = maze.grid[r][c].walls;
+  const up = floorSides(maze);
+  if (walls.length <= up) return;
+  const [cx, cy] = cellCenter(maze, r, c, cellSize);
+  const s = cellSize / 4;
+  const chevron = (dir: number) => {
+    path.moveTo(cx - s, cy);
+    path.lineTo(cx, cy + dir * s);
+    path.lineTo(cx + s, cy);
+  };
+  if (!walls[up]) chevron(-1);
+  if (!walls[up + 1]) chevron(1);
+}
+
+// Neighbour offsets by side, for even and odd hex rows and for squares.
+const hexDirs = [
+  [[-1, 0], [0, 1], [1, 0], [1, -1], [0, -1], [-1, -1]],
+  [[-1, 1], [0, 1], [1, 1], [1, 0], [0, -1], [-1, 0]],
+];
+const squareDirs = [[-1, 0], [0, 1], [1, 0], [0, -1]];
+
+// Whether leaving a cell through side crosses a joined edge of the grid.
+function wraps(maze: MazeData, r: number, c: number, side: number) {
+  if (!maze.wrap || side >= floorSides(maze)) return false;
+  const row = r % maze.rows;
+  const [dr, dc] =
+    maze.topology === "hex" ? hexDirs[row & 1][side] : squareDirs[side];
+  const nr = row + dr,
+    nc = c + dc;
+  if (nc < 0 || nc >= maze.cols) return true;
+  return maze.wrap === "torus" && (nr < 0 || nr >= maze.rows);
+}
+
+// Traces a chevron of arm length s with its tip at (mx, my), pointing
+// along (dx, dy).
+function addChevron(path: Path2D, [mx, my]: Pt, [dx, dy]: Pt, s: number) {
+  const len = Math.hypot(dx, dy);
+  const ux = dx / len,
+    uy = dy / len;
+  path.moveTo(mx - ux * s - uy * s, my - uy * s + ux * s);
+  path.lineTo(mx, my);
+  path.lineTo(mx - ux * s + uy * s, my - uy * s - ux * s);
+}
+
+// Midpoint of wall side of a cell.
+function wallMidpoint(
+  maze: MazeData,
+  r: number,
+  c: number,
+  side: number,
+  cellSize: number
+): Pt {
+  if (maze.topology === "polar") {
+    const { row, x } = locate(maze, r, cellSize);
+    const inner = row * cellSize,
+      outer = (row + 1) * cellSize,
+      middle = (row + 0.5) * cellSize;
+    const [t0, t1] = polarSpan(maze, r, c);
+    const mid = (t0 + t1) / 2;
+    const at = (radius: number, angle: number) =>
+      polarPoint(maze, x, radius, angle, cellSize);
+    if (side === 0) return at(inner, mid);
+    if (side === 1) return at(middle, t1);
+    if (side === 2) return at(outer, polarSplits(maze, row) ? (t0 + mid) / 2 : mid);
+    if (side === 3) return at(outer, (mid + t1) / 2);
+    return at(middle, t0);
+  }
+  const corners = cellCorners(maze, r, c, cellSize);
+  const [ax, ay] = corners[side];
+  const [bx, by] = corners[(side + 1) % corners.length];
+  return [(ax + bx) / 2, (ay + by) / 2];
+}
+
+// Marks open passages across a joined edge with a chevron on the wall,
+// pointing out of the cell.
+export function addWrapMarkers(
+  path: Path2D,
+  maze: MazeData,
+  r: number,
+  c: number,
+  cellSize: number
+) {
+  const [cx, cy] = cellCenter(maze, r, c, cellSize);
+  maze.grid[r][c].walls.forEach((wall, side) => {
+    if (wall || !wraps(maze, r, c, side)) return;
+    const [mx, my] = wallMidpoint(maze, r, c, side, cellSize);
+    addChevron(path, [mx, my], [mx - cx, my - cy], cellSize / 4);
+  });
+}
+
+// Marks the open sides a cell cannot be left through with a chevron on
+// the wall, pointing into the cell.
+export function addOneWays(
+  path: Path2D,
+  maze: MazeData,
+  r: number,
+  c: number,
+  cellSize: number
+) {
+  const oneWay = maze.grid[r][c].one_way ?? 0;
+  if (!oneWay) return;
+  const [cx, cy] = cellCenter(maze, r, c, cellSize);
+  maze.grid[r][c].walls.forEach((wall, side) => {
+    if (wall || !(oneWay & (1 << side)) || !hasSide(maze, r, side)) return;
+    const [mx, my] = wallMidpoint(maze, r, c, side, cellSize);
+    addChevron(path, [mx, my], [cx - mx, cy - my], cellSize / 4);
+  });
+}
+
+export function addCellShape(
+  path: Path2D,
+  maze: MazeData,
+  r: number,
+  c: number,
+  cellSize: number
+) {
+  if (maze.topology === "polar") {
+    const { row, x } = locate(maze, r, cellSize);
+    const centre = maze.rows * cellSize;
+    const [t0, t1] = polarSpan(maze, r, c);
+    const q = Math.PI / 2;
+    path.moveTo(...polarPoint(maze, x, row * cellSize, t0, cellSize));
+    path.arc(x + centre, centre, (row + 1) * cellSize, t0 - q, t1 - q);
+    path.arc(x + centre, centre, row * cellSize, t1 - q, t0 - q, true);
+    path.closePath();
+    return;
+  }
+  const corners = cellCorners(maze, r, c, cellSize);
+  path.moveTo(...corners[0]);
+  corners.slice(1).forEach((p) => path.lineTo(...p));
+  path.closePath();
+}
+
+// Traces a square cell of a weave maze as a corridor inset from the cell's
+// edges, so a passage running under a crossing shows in the gaps either
+// side of the bridge over it.
+export function addWeaveCell(
+  path: Path2D,
+  maze: MazeData,
+  r: number,
+  c: number,
+  cellSize: number
+) {
+  const { walls, tunnel } = maze.grid[r][c];
+  const [[x1, y1], , [x4, y4]] = cellCorners(maze, r, c, cellSize);
+  const inset = cellSize / 5;
+  const x2 = x1 + inset,
+    y2 = y1 + inset,
+    x3 = x4 - inset,
+    y3 = y4 - inset;
+  const line = (xa: number, ya: number, xb: number, yb: number) => {
+    path.moveTo(xa, ya);
+    path.lineTo(xb, yb);
+  };
+  const northSouth = (ya: number, yb: number) => {
+    line(x2, ya, x2, yb);
+    line(x3, ya, x3, yb);
+  };
+  const eastWest = (xa: number, xb: number) => {
+    line(xa, y2, xb, y2);
+    line(xa, y3, xb, y3);
+  };
+
+  if (!walls[0]) northSouth(y1, y2);
+  else line(x2, y2, x3, y2);
+  if (!walls[1]) eastWest(x3, x4);
+  else line(x3, y2, x3, y3);
+  if (!walls[2]) northSouth(y3, y4);
+  else line(x2, y3, x3, y3);
+  if (!walls[3]) eastWest(x1, x2);
+  else line(x2, y2, x2, y3);
+
+  if (tunnel) {
+    if (!walls[0]) {
+      eastWest(x3, x4);
+      eastWest(x1, x2);
+    } else {
+      northSouth(y1, y2);
+      northSouth(y3, y4);
+    }
+  }
+}
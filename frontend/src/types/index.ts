@@ -40,10 +40,48 @@ export interface MazeData {
   cols: number;
   start: [number, number];
   end: [number, number];
+  topology?: "square" | "hex" | "polar";
+  levels?: number; // floors stacked in grid, rows at a time
+  wrap?: "cylinder" | "torus";
+  placement?: "random" | "farthest" | "min_path" | "interior" | "corners";
+  portals?: [[number, number], [number, number]][]; // pairs of joined cells
   grid: Array<
     Array<{
-      walls: [boolean, boolean, boolean, boolean];
-      wall_weights: [number, number, number, number];
+      walls: boolean[];
+      wall_weights: number[];
+      masked?: boolean;
+      tunnel?: boolean;
+      key?: number; // key lying here, numbered from 1
+      door?: number; // key needed to enter
+      one_way?: number; // bit per open side that cannot be left through
+      cost?: number; // terrain cost to enter; missing or 1 is road
     }>
   >;
+  trace?: TraceEvent[];
+}
+
+export interface TraceEvent {
+  type: "visit" | "remove" | "add" | "merge" | "backtrack" | "clear" | "tunnel";
+  cells?: [number, number][];
+}
+
+export interface AlgorithmParam {
+  name: string;
+  type: "int" | "float" | "string" | "file";
+  default?: string;
+  min?: number;
+  max?: number;
+  description: string;
+}
+
+export interface AlgorithmInfo {
+  name: string;
+  description: string;
+  params: AlgorithmParam[];
+}
+
+export interface AlgorithmCatalog {
+  generators: AlgorithmInfo[];
+  solvers: AlgorithmInfo[];
+  options: AlgorithmParam[];
 }